@@ -2,113 +2,328 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"strconv"
+	"sync"
 	"time"
 
+	"mangahub-desktop/backend/config"
+	"mangahub-desktop/backend/httpclient"
+	"mangahub-desktop/backend/netwatch"
+	"mangahub-desktop/backend/probe"
 	"mangahub-desktop/backend/services"
+	"mangahub-desktop/backend/transport"
 	"mangahub-desktop/backend/udpclient"
 	"mangahub-desktop/backend/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
-	ctx     context.Context
-	base    string
-	Auth    *services.AuthService
-	Library *services.LibraryService
-	Notify  *services.NotifyService
-	Manga   *services.MangaService
-	Chat    *services.ChatService
-	Sync    *services.SyncService
-	GRPC    *services.GRPCService
-	Admin   *services.AdminService
+	ctx         context.Context
+	cfg         *config.Config
+	Auth        *services.AuthService
+	Identity    *services.IdentityService
+	Library     *services.LibraryService
+	Notify      *services.NotifyService
+	Manga       *services.MangaService
+	Chat        *services.ChatService
+	Sync        *services.SyncService
+	GRPC        *services.GRPCService
+	Admin       *services.AdminService
+	Settings    *services.SettingsService
+	Session     *services.SessionService
+	Accounts    *services.AccountService
+	Import      *services.ImportService
+	Export      *services.ExportService
+	Connections *services.ConnectionManager
+	Supervisor  *services.Supervisor
+	netWatch    *netwatch.Watcher
+	client      *httpclient.Client
+
+	startupMu    sync.Mutex
+	startupState StartupState
+}
+
+// Startup phases reported as startup:progress
+const (
+	StartupChecking      = "checking"       // looking for a stored session
+	StartupRestoring     = "restoring"      // discovering the server and starting services
+	StartupVerifying     = "verifying"      // confirming the session with the server
+	StartupReady         = "ready"          // session restored, skip the login screen
+	StartupLoginRequired = "login_required" // no usable session
+)
+
+// StartupState describes how far session restore got
+type StartupState struct {
+	Phase    string `json:"phase"`
+	Username string `json:"username,omitempty"`
+	// Offline is set when the session was restored without reaching the server
+	Offline bool   `json:"offline"`
+	Error   string `json:"error,omitempty"`
 }
 
 func NewApp() *App {
-	// Initialize with placeholder, will be updated after server discovery
-	base := "http://localhost:8080"
+	cfg, err := config.Load()
+	if err != nil {
+		log.Printf("⚠️ Failed to load config, using defaults: %v", err)
+		if cfg == nil {
+			cfg = &config.Config{
+				ActiveProfile: config.DefaultProfileName,
+				Profiles:      []config.Profile{config.DefaultProfile()},
+			}
+		}
+	}
+
+	// Start from the active profile, will be updated after server discovery
+	profile := cfg.Active()
+	base := profile.HTTPBase
 
-	syncService := services.NewSyncService()
+	conns := services.NewConnectionManager()
+	// One REST client shared by every HTTP service; it also tracks reachability
+	client := httpclient.New(services.NewRESTTracker(conns, transport.HTTPTransport()))
+	// Every REST service reads the base URL through one endpoint, so a
+	// profile switch or failover rebinds them all at once
+	rest := services.NewEndpoint(base)
+
+	syncService := services.NewSyncService(conns)
+	identity := services.NewIdentityService(rest, client)
 
 	app := &App{
-		base:    base,
-		Auth:    services.NewAuthService(base),
-		Library: services.NewLibraryService(base),
-		Manga:   services.NewMangaService(base),
-		Chat:    services.NewChatService(base),
-		Sync:    syncService,
-		GRPC:    services.NewGRPCService(),
-		Admin:   services.NewAdminService(base),
+		cfg:         cfg,
+		client:      client,
+		Identity:    identity,
+		Auth:        services.NewAuthService(rest, client, identity),
+		Library:     services.NewLibraryService(rest, client, conns),
+		Manga:       services.NewMangaService(rest, client),
+		Chat:        services.NewChatService(profile.WSBase, conns),
+		Sync:        syncService,
+		GRPC:        services.NewGRPCService(conns, identity),
+		Admin:       services.NewAdminService(rest, client),
+		Connections: conns,
 		// Pass syncService to NotifyService so it can auto-start TCP
-		Notify: services.NewNotifyService(syncService),
+		Notify: services.NewNotifyService(syncService, conns),
+	}
+	app.Settings = services.NewSettingsService(
+		cfg,
+		rest,
+		app.Identity,
+		app.Chat,
+		app.Sync,
+		app.Notify,
+	)
+	if err := app.Settings.ApplyProfile(profile); err != nil {
+		log.Printf("⚠️ %v", err)
 	}
+	app.Supervisor = services.NewSupervisor(conns, app.Chat, app.Sync, app.Notify)
+	app.Session = services.NewSessionService(app.Auth, app.Chat, app.Sync, app.Notify)
+	app.Accounts = services.NewAccountService(app.Identity, app.Session, app.Chat, app.Sync, app.Notify)
+	app.Import = services.NewImportService(app.Library, app.Manga)
+	app.Export = services.NewExportService(app.Library, app.Manga)
+	app.netWatch = netwatch.New()
+
+	// Any authenticated request rejected with 401 means the session is gone
+	client.OnUnauthorized = app.onUnauthorized
+
 	// Set callback to initialize services after login
 	app.Auth.OnLoginSuccess = app.InitializeAfterLogin
+	app.Auth.OnLogout = app.teardownSession
+	app.Settings.OnProfileSwitch = app.InitializeServices
+	app.Supervisor.Check = app.checkServer
+	app.Supervisor.Rediscover = app.InitializeServices
+	app.Session.OnExpired = app.Supervisor.Stop
+	app.Accounts.OnSwitch = app.InitializeAfterLogin
 
 	return app
 }
 
-// InitializeServices discovers the server and updates all service base URLs
+// InitializeServices resolves the server for the active profile and updates all service endpoints
 func (a *App) InitializeServices() error {
-	log.Println("🔍 Discovering server via UDP...")
+	profile := a.Settings.GetActiveProfile()
 
-	// Try to discover server IP
-	serverIP, err := a.DiscoverServer()
-	if err != nil {
-		log.Printf("⚠️ Server discovery failed, using baseURL: %v", err)
-		// Fall back to the original base URL (ngrok or configured URL)
-		a.Auth.BaseURL = a.base
-		a.Library.BaseURL = a.base
-		a.Manga.BaseURL = a.base
-		a.Admin.BaseURL = a.base
-		// For websocket, replace http with ws
-		wsBase := a.base
-		if len(wsBase) > 7 && wsBase[:4] == "http" {
-			wsBase = "ws" + wsBase[4:]
+	if !profile.Discover {
+		if err := a.Settings.ApplyProfile(profile); err != nil {
+			return err
 		}
-		a.Chat.SetBaseURL(wsBase)
-		log.Printf("📍 Using fallback URL: %s", a.base)
+		udpclient.SetLocalIP("")
+		// Static profiles reach UDP notifications on the HTTP host
+		if err := utils.SaveSelectedServer(utils.SelectedServer{
+			Name: profile.Name,
+			Host: profile.Host(),
+			Port: profile.DiscoveryPort,
+		}); err != nil {
+			log.Printf("Failed to save server address: %v", err)
+		}
+		log.Printf("📍 Using profile %q: %s", profile.Name, profile.HTTPBase)
 		return nil
 	}
 
-	log.Printf("✅ Discovered server IP: %s", serverIP)
+	// Fast path: reuse the cached server if it still answers
+	if ok, err := a.reconnectCached(profile); ok {
+		return err
+	}
+
+	log.Printf("🔍 Discovering server (%s)...", profile.DiscoveryStrategy)
+
+	// Try to discover the server
+	server, err := a.resolveServer(profile)
+	if err != nil {
+		log.Printf("⚠️ Server discovery failed, using profile URL: %v", err)
+		// Fall back to the profile's configured URL (ngrok or remote server)
+		log.Printf("📍 Using fallback URL: %s", profile.HTTPBase)
+		return a.Settings.ApplyProfile(profile)
+	}
+
+	log.Printf("✅ Discovered server IP: %s", server.Host)
+
+	// Update all services with the discovered IP and advertised ports
+	return a.Settings.ApplyProfile(
+		profile.WithHost(server.Host).WithPorts(server.HTTPPort, server.SyncPort, server.GRPCPort),
+	)
+}
+
+// reconnectCached probes the last used server (HTTP health plus TCP sync) and
+// binds the services to it on success, returning the binding error if any.
+// Servers that keep failing probes are skipped so discovery can find a better one.
+func (a *App) reconnectCached(profile config.Profile) (bool, error) {
+	selected, err := utils.LoadSelectedServer()
+	if err != nil {
+		return false, nil
+	}
+	if probe.IsFlaky(selected.Addr()) {
+		log.Printf("⚠️ Cached server %s is unreliable, skipping fast reconnect", selected.Addr())
+		return false, nil
+	}
+
+	candidate := profile.WithHost(selected.Host).WithPorts(selected.HTTPPort, selected.SyncPort, selected.GRPCPort)
+	syncAddr := net.JoinHostPort(selected.Host, strconv.Itoa(candidate.SyncPort))
 
-	// Update all HTTP services' BaseURL with discovered IP
-	httpBaseURL := fmt.Sprintf("http://%s:8080", serverIP)
-	wsBaseURL := fmt.Sprintf("ws://%s:8080", serverIP)
+	result := probe.Probe(selected.Addr(), candidate.HTTPBase, syncAddr, time.Second)
+	if err := probe.Record(result); err != nil {
+		log.Printf("Failed to record probe result: %v", err)
+	}
+	if !result.OK() {
+		log.Printf("⚠️ Cached server %s failed probe: %s", selected.Addr(), result.Error)
+		return false, nil
+	}
 
-	a.Auth.BaseURL = httpBaseURL
-	a.Library.BaseURL = httpBaseURL
-	a.Manga.BaseURL = httpBaseURL
-	a.Admin.BaseURL = httpBaseURL
-	a.Sync.SetBaseURL(serverIP)
-	a.Chat.SetBaseURL(wsBaseURL)
+	log.Printf("⚡ Reconnected to cached server %s (http %dms, sync %dms)",
+		selected.Addr(), result.HTTPLatencyMs, result.SyncLatencyMs)
+	udpclient.SetLocalIP(selected.LocalIP)
+	return true, a.Settings.ApplyProfile(candidate)
+}
 
-	log.Printf("📍 Updated service URLs to: %s", httpBaseURL)
+// checkServer probes the server the services are currently bound to
+func (a *App) checkServer() error {
+	profile := a.Settings.GetAppliedProfile()
+	syncAddr := net.JoinHostPort(profile.Host(), strconv.Itoa(profile.SyncPort))
 
+	key := syncAddr
+	if selected, err := utils.LoadSelectedServer(); err == nil {
+		key = selected.Addr()
+	}
+
+	result := probe.Probe(key, profile.HTTPBase, syncAddr, 2*time.Second)
+	if err := probe.Record(result); err != nil {
+		log.Printf("Failed to record probe result: %v", err)
+	}
+	if !result.OK() {
+		return errors.New(result.Error)
+	}
 	return nil
 }
 
-// DiscoverServer discovers the MangaHub server via UDP broadcast
+// DiscoverServer discovers the MangaHub server and returns its IP
 func (a *App) DiscoverServer() (string, error) {
-	serverAddr, err := udpclient.DiscoverUDPServer(3 * time.Second)
+	server, err := a.resolveServer(a.Settings.GetActiveProfile())
 	if err != nil {
 		return "", err
 	}
+	return server.Host, nil
+}
+
+// resolveServer runs discovery with the profile's strategy.
+// If the user previously picked a server and it still answers, it wins over
+// whichever server happens to respond fastest.
+func (a *App) resolveServer(profile config.Profile) (*utils.SelectedServer, error) {
+	selected, _ := utils.LoadSelectedServer()
+
+	servers, err := udpclient.Discover(profile.DiscoveryStrategy, 3*time.Second)
+	if err != nil {
+		if selected != nil {
+			log.Printf("⚠️ Discovery failed, using saved server %s", selected.Addr())
+			udpclient.SetLocalIP(selected.LocalIP)
+			return selected, nil
+		}
+		return nil, err
+	}
+
+	chosen := servers[0]
+	if selected != nil && !probe.IsFlaky(selected.Addr()) {
+		for _, s := range servers {
+			if s.Host == selected.Host && s.Port == selected.Port {
+				chosen = s
+				break
+			}
+		}
+	}
 
 	// Save the discovered server address
-	if err := utils.SaveUDPServerAddr(serverAddr); err != nil {
+	if err := saveDiscoveredServer(chosen); err != nil {
 		log.Printf("Failed to save server address: %v", err)
 	}
 
-	// Extract IP from address
-	serverIP, err := utils.LoadServerIPAddr()
-	if err != nil {
-		return "", err
+	// Send registration/subscription traffic out the interface the offer came in on
+	log.Printf("📶 Server %s found via %s on interface %q (%s)", chosen.Addr, chosen.Source, chosen.Interface, chosen.LocalIP)
+	udpclient.SetLocalIP(chosen.LocalIP)
+
+	return toSelectedServer(chosen), nil
+}
+
+// DiscoverServers lists every MangaHub server answering on the LAN
+func (a *App) DiscoverServers() ([]udpclient.DiscoveredServer, error) {
+	return udpclient.Discover(a.Settings.GetActiveProfile().DiscoveryStrategy, 3*time.Second)
+}
+
+// GetSelectedServer returns the persisted server choice, if any
+func (a *App) GetSelectedServer() (*utils.SelectedServer, error) {
+	return utils.LoadSelectedServer()
+}
+
+// SelectServer persists the user's server choice and rebinds the services to it
+func (a *App) SelectServer(server udpclient.DiscoveredServer) error {
+	if server.Host == "" || server.Port == 0 {
+		return fmt.Errorf("host and port required")
 	}
 
-	return serverIP, nil
+	if err := saveDiscoveredServer(server); err != nil {
+		return err
+	}
+
+	log.Printf("📌 Selected server %s (%s:%d)", server.Name, server.Host, server.Port)
+	return a.InitializeServices()
+}
+
+func saveDiscoveredServer(server udpclient.DiscoveredServer) error {
+	return utils.SaveSelectedServer(*toSelectedServer(server))
+}
+
+func toSelectedServer(server udpclient.DiscoveredServer) *utils.SelectedServer {
+	return &utils.SelectedServer{
+		Name:      server.Name,
+		Host:      server.Host,
+		Port:      server.Port,
+		LatencyMs: server.LatencyMs,
+		Interface: server.Interface,
+		LocalIP:   server.LocalIP,
+		HTTPPort:  server.HTTPPort,
+		SyncPort:  server.SyncPort,
+		GRPCPort:  server.GRPCPort,
+	}
 }
 
 func (a *App) startup(ctx context.Context) {
@@ -119,13 +334,76 @@ func (a *App) startup(ctx context.Context) {
 
 	// Set context for ALL services FIRST before any Start() calls
 	a.ctx = ctx
+	a.Connections.SetContext(ctx)
 	a.Notify.SetContext(ctx)
 	a.Chat.SetContext(ctx)
 	a.Sync.SetContext(ctx)
+	a.Supervisor.SetContext(ctx)
+	a.Session.SetContext(ctx)
+	a.Library.SetContext(ctx)
+	a.Accounts.SetContext(ctx)
+	a.Import.SetContext(ctx)
+	a.Export.SetContext(ctx)
+
+	// Re-register notifications whenever the local network changes
+	a.netWatch.Start(ctx, a.onNetworkChange)
 
-	// Don't discover server on startup - wait until after login
 	log.Println("All service contexts initialized")
-	utils.LogInfo("App started successfully - waiting for login to discover server")
+
+	// Pick up where the last run left off if the stored token is still good
+	a.setStartupState(StartupState{Phase: StartupChecking})
+	go a.restoreSession()
+}
+
+// restoreSession brings the services up for a stored, unexpired token;
+// without one the login screen is shown
+func (a *App) restoreSession() {
+	info := a.Session.GetSession()
+	if !info.LoggedIn || info.Expired {
+		utils.LogInfo("App started successfully - waiting for login to discover server")
+		a.setStartupState(StartupState{Phase: StartupLoginRequired})
+		return
+	}
+
+	utils.LogInfo(fmt.Sprintf("🔐 Restoring session for %s", info.Username))
+	a.setStartupState(StartupState{Phase: StartupRestoring, Username: info.Username})
+
+	if err := a.InitializeAfterLogin(); err != nil {
+		a.setStartupState(StartupState{Phase: StartupLoginRequired, Error: err.Error()})
+		return
+	}
+
+	a.setStartupState(StartupState{Phase: StartupVerifying, Username: info.Username})
+
+	identity, err := a.Identity.Current()
+	switch {
+	case httpclient.IsUnauthorized(err):
+		// The 401 already expired the session and paused the services
+		a.setStartupState(StartupState{Phase: StartupLoginRequired, Error: "session no longer valid"})
+	case err != nil:
+		// Keep the session; requests will work once the server is back
+		utils.LogError(fmt.Sprintf("🔐 Could not confirm session, continuing offline: %v", err))
+		a.setStartupState(StartupState{Phase: StartupReady, Username: info.Username, Offline: true, Error: err.Error()})
+	default:
+		utils.LogInfo(fmt.Sprintf("✅ Session restored for %s", identity.Username))
+		a.setStartupState(StartupState{Phase: StartupReady, Username: identity.Username})
+	}
+}
+
+// GetStartupState returns the session restore progress, for a frontend that
+// loaded after the startup:progress events were sent
+func (a *App) GetStartupState() StartupState {
+	a.startupMu.Lock()
+	defer a.startupMu.Unlock()
+	return a.startupState
+}
+
+func (a *App) setStartupState(state StartupState) {
+	a.startupMu.Lock()
+	a.startupState = state
+	a.startupMu.Unlock()
+
+	runtime.EventsEmit(a.ctx, "startup:progress", state)
 }
 
 // InitializeAfterLogin discovers the server and starts UDP/TCP connections after successful login
@@ -138,17 +416,108 @@ func (a *App) InitializeAfterLogin() error {
 		return err
 	}
 
-	// Start UDP notifications and TCP sync after services are initialized
-	if err := a.Notify.Start(); err != nil {
+	// Start UDP notifications and TCP sync after services are initialized;
+	// logging in again (possibly as another account) re-registers them
+	start := a.Notify.Start
+	if a.Notify.IsRunning() {
+		start = a.Notify.Restart
+	}
+	if err := start(); err != nil {
 		log.Printf("Failed to start NotifyService: %v", err)
 	}
 
+	// Watch the server and fail over if it disappears
+	a.Supervisor.Start()
+
+	// Renew the token before it runs out
+	if err := a.Session.Start(); err != nil {
+		log.Printf("Failed to start session renewal: %v", err)
+	}
+
+	// Send library changes this account queued while offline
+	go a.Library.ReplayPending()
+
 	log.Println("✅ Services initialized after login")
 	return nil
 }
 
+// logoutReplayTimeout bounds how long logout waits for queued library changes
+const logoutReplayTimeout = 5 * time.Second
+
+// teardownSession stops everything that runs under the logged-in identity.
+// It runs before the token is deleted so the server can be told to forget us.
+func (a *App) teardownSession() {
+	utils.LogInfo("👋 Logging out - tearing down services")
+
+	a.Session.Stop()
+	a.Supervisor.Stop()
+	// Send queued library changes while the token still works, but only if
+	// the server is answering and only briefly; whatever is left stays on
+	// disk for the next login
+	if a.Connections.Connected(services.TransportREST) {
+		done := make(chan error, 1)
+		go func() { done <- a.Library.ReplayPending() }()
+		select {
+		case err := <-done:
+			if err != nil {
+				utils.LogError(fmt.Sprintf("Library changes still queued at logout: %v", err))
+			}
+		case <-time.After(logoutReplayTimeout):
+			utils.LogError("Library changes still sending at logout, the rest stay queued")
+		}
+	}
+	a.Notify.Unregister()
+	a.Sync.Stop()
+	a.Chat.Disconnect()
+	a.Connections.Reset()
+
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "auth:logged_out")
+	}
+}
+
+// onUnauthorized ends the session once the stored token is no longer accepted
+func (a *App) onUnauthorized() {
+	utils.LogInfo("🔒 Server rejected the stored token")
+	a.Session.Expire("rejected by server")
+}
+
+// onNetworkChange rebinds UDP notifications and TCP sync after the laptop
+// switched networks or resumed from sleep
+func (a *App) onNetworkChange(change netwatch.Change) {
+	utils.LogInfo(fmt.Sprintf("🌐 Network change detected (%s): %v", change.Reason, change.Addresses))
+	runtime.EventsEmit(a.ctx, "network:changed", change)
+
+	// Nothing registered yet - login will set everything up
+	if !a.Notify.IsRunning() && !a.Sync.IsRunning() {
+		return
+	}
+
+	// The interface we reached the server through may be gone; let the
+	// kernel pick the new route and remember it
+	if selected, err := utils.LoadSelectedServer(); err == nil {
+		if serverAddr, err := net.ResolveUDPAddr("udp", selected.Addr()); err == nil {
+			if ip := utils.GetReplyIP(serverAddr); ip != nil && ip.String() != selected.LocalIP {
+				selected.LocalIP = ip.String()
+				selected.Interface = ""
+				utils.SaveSelectedServer(*selected)
+			}
+		}
+		udpclient.SetLocalIP(selected.LocalIP)
+	}
+
+	if err := a.Notify.Restart(); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to re-register notifications: %v", err))
+		// The server may be unreachable from the new network
+		a.Supervisor.Trigger()
+	}
+}
+
 func (a *App) shutdown(ctx context.Context) {
 	utils.LogInfo("App shutting down")
+	a.netWatch.Stop()
+	a.Session.Stop()
+	a.Supervisor.Stop()
 	if a.Notify != nil {
 		a.Notify.Stop()
 	}
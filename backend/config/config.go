@@ -0,0 +1,401 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"mangahub-desktop/backend/utils"
+)
+
+const (
+	DefaultProfileName   = "local"
+	DefaultHTTPBase      = "http://localhost:8080"
+	DefaultSyncPort      = 9090
+	DefaultDiscoveryPort = 9091
+	DefaultGRPCPort      = 9092
+	DefaultNotifyPort    = 3002
+
+	DefaultDiscoveryStrategy = "auto"
+)
+
+// Profile describes how to reach one MangaHub server
+type Profile struct {
+	Name          string `json:"name"`
+	HTTPBase      string `json:"http_base"`
+	WSBase        string `json:"ws_base"`
+	SyncPort      int    `json:"sync_port"`
+	DiscoveryPort int    `json:"discovery_port"`
+	GRPCPort      int    `json:"grpc_port"`
+	NotifyPort    int    `json:"notify_port"`
+	// Discover enables LAN discovery; HTTPBase/WSBase are then only a fallback
+	Discover bool `json:"discover"`
+	// DiscoveryStrategy is auto, mdns or broadcast (see udpclient.Discover)
+	DiscoveryStrategy string `json:"discovery_strategy"`
+	TLS               TLS    `json:"tls"`
+	Proxy             Proxy  `json:"proxy"`
+}
+
+// TLS secures REST, WebSocket, gRPC and TCP sync for a profile
+type TLS struct {
+	Enabled bool `json:"enabled"`
+	// CAFile is a PEM bundle trusted in addition to the system roots
+	CAFile string `json:"ca_file,omitempty"`
+	// Pins are base64 SHA-256 hashes of a certificate's SubjectPublicKeyInfo
+	// ("sha256/" prefix optional); when set, the chain must contain one of them
+	Pins []string `json:"pins,omitempty"`
+	// ServerName overrides the name verified against the certificate, for
+	// servers reached by a discovered IP
+	ServerName string `json:"server_name,omitempty"`
+}
+
+// Proxy routes every TCP transport through an HTTP(S) or SOCKS5 proxy
+type Proxy struct {
+	// URL is an http://, https:// or socks5:// proxy, credentials allowed;
+	// empty uses HTTP_PROXY/HTTPS_PROXY/NO_PROXY from the environment
+	URL string `json:"url,omitempty"`
+	// NoProxy lists hosts reached directly, in NO_PROXY syntax
+	NoProxy string `json:"no_proxy,omitempty"`
+	// Disabled connects directly, ignoring the environment too
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Config is the persisted set of server profiles
+type Config struct {
+	ActiveProfile string    `json:"active_profile"`
+	Profiles      []Profile `json:"profiles"`
+
+	mu sync.Mutex
+}
+
+func DefaultProfile() Profile {
+	return Profile{
+		Name:              DefaultProfileName,
+		HTTPBase:          DefaultHTTPBase,
+		WSBase:            wsFromHTTP(DefaultHTTPBase),
+		SyncPort:          DefaultSyncPort,
+		DiscoveryPort:     DefaultDiscoveryPort,
+		GRPCPort:          DefaultGRPCPort,
+		NotifyPort:        DefaultNotifyPort,
+		Discover:          true,
+		DiscoveryStrategy: DefaultDiscoveryStrategy,
+	}
+}
+
+func configPath() string {
+	return filepath.Join(utils.ConfigDir(), "config.json")
+}
+
+// Load reads the config file, creating it with the default profile if missing
+func Load() (*Config, error) {
+	cfg := &Config{}
+
+	data, err := os.ReadFile(configPath())
+	switch {
+	case os.IsNotExist(err):
+		cfg.Profiles = []Profile{DefaultProfile()}
+		cfg.ActiveProfile = DefaultProfileName
+		if err := cfg.Save(); err != nil {
+			return cfg, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", configPath(), err)
+		}
+	}
+
+	if len(cfg.Profiles) == 0 {
+		cfg.Profiles = []Profile{DefaultProfile()}
+	}
+	for i := range cfg.Profiles {
+		cfg.Profiles[i] = cfg.Profiles[i].withDefaults()
+	}
+	if _, ok := cfg.find(cfg.ActiveProfile); !ok {
+		cfg.ActiveProfile = cfg.Profiles[0].Name
+	}
+
+	return cfg, nil
+}
+
+// Save writes the config file
+func (c *Config) Save() error {
+	c.mu.Lock()
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(utils.ConfigDir(), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(configPath(), data, 0600)
+}
+
+// Active returns the active profile with environment overrides applied
+func (c *Config) Active() Profile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	name := c.ActiveProfile
+	if env := os.Getenv("MANGAHUB_PROFILE"); env != "" {
+		if _, ok := c.find(env); ok {
+			name = env
+		}
+	}
+
+	p, ok := c.find(name)
+	if !ok {
+		p = DefaultProfile()
+	}
+	return applyEnv(p)
+}
+
+// List returns a copy of all stored profiles
+func (c *Config) List() []Profile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	profiles := make([]Profile, len(c.Profiles))
+	copy(profiles, c.Profiles)
+	return profiles
+}
+
+// Upsert adds a profile or replaces the one with the same name
+func (c *Config) Upsert(p Profile) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return fmt.Errorf("profile name required")
+	}
+	if p.HTTPBase == "" {
+		return fmt.Errorf("http base URL required")
+	}
+	if _, err := url.ParseRequestURI(p.HTTPBase); err != nil {
+		return fmt.Errorf("invalid http base URL: %w", err)
+	}
+	p = p.withDefaults()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == p.Name {
+			c.Profiles[i] = p
+			return nil
+		}
+	}
+	c.Profiles = append(c.Profiles, p)
+	return nil
+}
+
+// Remove deletes a profile; the active profile cannot be removed
+func (c *Config) Remove(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if name == c.ActiveProfile {
+		return fmt.Errorf("cannot remove the active profile")
+	}
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == name {
+			c.Profiles = append(c.Profiles[:i], c.Profiles[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("profile %q not found", name)
+}
+
+// SetActive switches the active profile
+func (c *Config) SetActive(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.find(name); !ok {
+		return fmt.Errorf("profile %q not found", name)
+	}
+	c.ActiveProfile = name
+	return nil
+}
+
+func (c *Config) find(name string) (Profile, bool) {
+	for _, p := range c.Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Profile{}, false
+}
+
+// Host returns the server host name from the HTTP base URL
+func (p Profile) Host() string {
+	u, err := url.Parse(p.HTTPBase)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// WithHost returns a copy of the profile pointing at host, keeping schemes and ports
+func (p Profile) WithHost(host string) Profile {
+	p.HTTPBase = replaceHost(p.HTTPBase, host)
+	p.WSBase = replaceHost(p.WSBase, host)
+	return p
+}
+
+// WithPorts returns a copy of the profile using server-advertised ports;
+// zero values keep the profile's own
+func (p Profile) WithPorts(httpPort, syncPort, grpcPort int) Profile {
+	if httpPort > 0 {
+		p.HTTPBase = replacePort(p.HTTPBase, httpPort)
+		p.WSBase = replacePort(p.WSBase, httpPort)
+	}
+	if syncPort > 0 {
+		p.SyncPort = syncPort
+	}
+	if grpcPort > 0 {
+		p.GRPCPort = grpcPort
+	}
+	return p
+}
+
+func (p Profile) withDefaults() Profile {
+	if p.HTTPBase == "" {
+		p.HTTPBase = DefaultHTTPBase
+	}
+	p.HTTPBase = strings.TrimRight(p.HTTPBase, "/")
+	if p.WSBase == "" {
+		p.WSBase = wsFromHTTP(p.HTTPBase)
+	}
+	p.WSBase = strings.TrimRight(p.WSBase, "/")
+	if p.TLS.Enabled {
+		p.HTTPBase = upgradeScheme(p.HTTPBase)
+		p.WSBase = upgradeScheme(p.WSBase)
+	}
+	if p.SyncPort == 0 {
+		p.SyncPort = DefaultSyncPort
+	}
+	if p.DiscoveryPort == 0 {
+		p.DiscoveryPort = DefaultDiscoveryPort
+	}
+	if p.GRPCPort == 0 {
+		p.GRPCPort = DefaultGRPCPort
+	}
+	if p.NotifyPort == 0 {
+		p.NotifyPort = DefaultNotifyPort
+	}
+	if p.DiscoveryStrategy == "" {
+		p.DiscoveryStrategy = DefaultDiscoveryStrategy
+	}
+	return p
+}
+
+// applyEnv overrides profile fields from MANGAHUB_* environment variables.
+// Pointing MANGAHUB_HTTP_BASE at a server disables discovery unless
+// MANGAHUB_DISCOVER says otherwise.
+func applyEnv(p Profile) Profile {
+	if v := os.Getenv("MANGAHUB_HTTP_BASE"); v != "" {
+		p.HTTPBase = strings.TrimRight(v, "/")
+		p.WSBase = wsFromHTTP(p.HTTPBase)
+		p.Discover = false
+	}
+	if v := os.Getenv("MANGAHUB_WS_BASE"); v != "" {
+		p.WSBase = strings.TrimRight(v, "/")
+	}
+	if v := os.Getenv("MANGAHUB_TLS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			p.TLS.Enabled = b
+		}
+	}
+	if v := os.Getenv("MANGAHUB_CA_FILE"); v != "" {
+		p.TLS.CAFile = v
+	}
+	if v := os.Getenv("MANGAHUB_TLS_PINS"); v != "" {
+		p.TLS.Pins = strings.Split(v, ",")
+	}
+	if p.TLS.Enabled {
+		p.HTTPBase = upgradeScheme(p.HTTPBase)
+		p.WSBase = upgradeScheme(p.WSBase)
+	}
+	switch v := os.Getenv("MANGAHUB_PROXY"); v {
+	case "":
+	case "none", "direct":
+		p.Proxy.Disabled = true
+	default:
+		p.Proxy.URL = v
+		p.Proxy.Disabled = false
+	}
+	envPort("MANGAHUB_SYNC_PORT", &p.SyncPort)
+	envPort("MANGAHUB_DISCOVERY_PORT", &p.DiscoveryPort)
+	envPort("MANGAHUB_GRPC_PORT", &p.GRPCPort)
+	envPort("MANGAHUB_NOTIFY_PORT", &p.NotifyPort)
+	if v := os.Getenv("MANGAHUB_DISCOVERY_STRATEGY"); v != "" {
+		p.DiscoveryStrategy = v
+	}
+	if v := os.Getenv("MANGAHUB_DISCOVER"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			p.Discover = b
+		}
+	}
+	return p
+}
+
+func envPort(key string, dst *int) {
+	v := os.Getenv(key)
+	if v == "" {
+		return
+	}
+	if port, err := strconv.Atoi(v); err == nil && port > 0 && port < 65536 {
+		*dst = port
+	}
+}
+
+func wsFromHTTP(base string) string {
+	switch {
+	case strings.HasPrefix(base, "https://"):
+		return "wss://" + strings.TrimPrefix(base, "https://")
+	case strings.HasPrefix(base, "http://"):
+		return "ws://" + strings.TrimPrefix(base, "http://")
+	}
+	return base
+}
+
+// upgradeScheme switches http/ws URLs to https/wss
+func upgradeScheme(base string) string {
+	switch {
+	case strings.HasPrefix(base, "http://"):
+		return "https://" + strings.TrimPrefix(base, "http://")
+	case strings.HasPrefix(base, "ws://"):
+		return "wss://" + strings.TrimPrefix(base, "ws://")
+	}
+	return base
+}
+
+func replaceHost(raw, host string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	if port := u.Port(); port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	} else {
+		u.Host = host
+	}
+	return u.String()
+}
+
+func replacePort(raw string, port int) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), strconv.Itoa(port))
+	return u.String()
+}
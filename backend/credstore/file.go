@@ -0,0 +1,199 @@
+package credstore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	credentialsFile     = "credentials.enc"
+	keySourceMachine    = "machine-id"
+	keySourcePassphrase = "passphrase"
+)
+
+// envelope is the on-disk format of the encrypted file
+type envelope struct {
+	Version   int    `json:"version"`
+	KeySource string `json:"key_source"`
+	Salt      []byte `json:"salt"`
+	Nonce     []byte `json:"nonce"`
+	Data      []byte `json:"data"`
+}
+
+// File stores secrets as one AES-256-GCM encrypted JSON map. The key is
+// derived with scrypt from a passphrase or, without one, the machine ID, so
+// the file is useless when copied to another machine.
+type File struct {
+	mu     sync.Mutex
+	path   string
+	secret []byte
+	source string
+	// The scrypt key is expensive, so it is derived once per salt
+	salt []byte
+	aead cipher.AEAD
+}
+
+func NewFile(dir, passphrase string) (*File, error) {
+	f := &File{path: filepath.Join(dir, credentialsFile)}
+
+	if passphrase != "" {
+		f.secret = []byte(passphrase)
+		f.source = keySourcePassphrase
+	} else {
+		id, err := machineID()
+		if err != nil {
+			return nil, fmt.Errorf("no passphrase and no machine ID: %w", err)
+		}
+		f.secret = []byte("mangahub-desktop:" + id)
+		f.source = keySourceMachine
+	}
+	return f, nil
+}
+
+func (f *File) Get(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (f *File) Set(key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	secrets[key] = value
+	return f.save(secrets)
+}
+
+func (f *File) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	secrets, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[key]; !ok {
+		return nil
+	}
+	delete(secrets, key)
+	return f.save(secrets)
+}
+
+func (f *File) Name() string {
+	return BackendFile
+}
+
+func (f *File) load() (map[string]string, error) {
+	secrets := map[string]string{}
+
+	data, err := os.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return secrets, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("corrupt credential file: %w", err)
+	}
+	if env.KeySource != f.source {
+		return nil, fmt.Errorf("credential file is protected by a %s, not a %s", env.KeySource, f.source)
+	}
+
+	gcm, err := f.cipher(env.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, env.Nonce, env.Data, []byte(env.KeySource))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt credential file (wrong passphrase or machine?)")
+	}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("corrupt credential file: %w", err)
+	}
+	return secrets, nil
+}
+
+func (f *File) save(secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	env := envelope{Version: 1, KeySource: f.source, Salt: f.salt}
+	if env.Salt == nil {
+		env.Salt = make([]byte, 16)
+		if _, err := rand.Read(env.Salt); err != nil {
+			return err
+		}
+	}
+	gcm, err := f.cipher(env.Salt)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return err
+	}
+	env.Data = gcm.Seal(nil, env.Nonce, plain, []byte(env.KeySource))
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+
+	// Write then rename so a crash never leaves a half-written file
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path)
+}
+
+func (f *File) cipher(salt []byte) (cipher.AEAD, error) {
+	if f.aead != nil && bytes.Equal(salt, f.salt) {
+		return f.aead, nil
+	}
+
+	key, err := scrypt.Key(f.secret, salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	f.salt, f.aead = salt, aead
+	return aead, nil
+}
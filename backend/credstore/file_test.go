@@ -0,0 +1,126 @@
+package credstore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFile(dir, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get on empty store: got %v, want ErrNotFound", err)
+	}
+	if err := store.Set("token", "secret-jwt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("token:alice", "alice-jwt"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, credentialsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret-jwt")) {
+		t.Fatal("credential file holds the secret in plaintext")
+	}
+
+	// A fresh store derives the key again from the same passphrase
+	reopened, err := NewFile(dir, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.Get("token"); err != nil || got != "secret-jwt" {
+		t.Fatalf("Get after reopen: got %q, %v", got, err)
+	}
+
+	if err := reopened.Delete("token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Delete("token"); err != nil {
+		t.Fatalf("Delete of a missing key: %v", err)
+	}
+	if _, err := store.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: got %v, want ErrNotFound", err)
+	}
+	if got, err := store.Get("token:alice"); err != nil || got != "alice-jwt" {
+		t.Fatalf("Delete removed another key: got %q, %v", got, err)
+	}
+}
+
+func TestFileWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFile(dir, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("token", "secret-jwt"); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong, err := NewFile(dir, "battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := wrong.Get("token"); err == nil {
+		t.Fatalf("Get with the wrong passphrase returned %q", got)
+	}
+	// Nothing may be written over a file that could not be decrypted
+	if err := wrong.Set("token", "other"); err == nil {
+		t.Fatal("Set with the wrong passphrase succeeded")
+	}
+	if got, err := store.Get("token"); err != nil || got != "secret-jwt" {
+		t.Fatalf("Get after failed overwrite: got %q, %v", got, err)
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("legacy-jwt\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemory()
+	if err := MigrateFile(store, "token", path); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get("token"); err != nil || got != "legacy-jwt" {
+		t.Fatalf("migrated secret: got %q, %v", got, err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("plaintext file still exists: %v", err)
+	}
+
+	// Nothing left to migrate
+	if err := MigrateFile(store, "token", path); err != nil {
+		t.Fatalf("MigrateFile without a file: %v", err)
+	}
+}
+
+func TestMigrateEmptyFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token")
+	if err := os.WriteFile(path, []byte("  \n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	store := NewMemory()
+	if err := MigrateFile(store, "token", path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("empty file stored a secret: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("empty plaintext file still exists: %v", err)
+	}
+}
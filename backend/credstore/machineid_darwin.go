@@ -0,0 +1,21 @@
+package credstore
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+)
+
+var platformUUID = regexp.MustCompile(`"IOPlatformUUID" = "([^"]+)"`)
+
+func machineID() (string, error) {
+	out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+	if err != nil {
+		return "", err
+	}
+	match := platformUUID.FindSubmatch(out)
+	if match == nil {
+		return "", fmt.Errorf("IOPlatformUUID not found")
+	}
+	return string(match[1]), nil
+}
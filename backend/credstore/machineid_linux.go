@@ -0,0 +1,18 @@
+package credstore
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func machineID() (string, error) {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if data, err := os.ReadFile(path); err == nil {
+			if id := strings.TrimSpace(string(data)); id != "" {
+				return id, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("machine-id not found")
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !windows && !darwin
+
+package credstore
+
+import "fmt"
+
+func machineID() (string, error) {
+	return "", fmt.Errorf("machine ID not supported on this platform, set MANGAHUB_CREDSTORE_PASSPHRASE")
+}
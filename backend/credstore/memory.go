@@ -0,0 +1,43 @@
+package credstore
+
+import "sync"
+
+// Memory is an in-process Store, for tests and sessions that must not
+// persist anything
+type Memory struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func NewMemory() *Memory {
+	return &Memory{secrets: map[string]string{}}
+}
+
+func (m *Memory) Get(key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.secrets[key]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func (m *Memory) Set(key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.secrets[key] = value
+	return nil
+}
+
+func (m *Memory) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.secrets, key)
+	return nil
+}
+
+func (m *Memory) Name() string {
+	return BackendMemory
+}
@@ -0,0 +1,210 @@
+package credstore
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	secretsService    = "org.freedesktop.secrets"
+	secretsPath       = dbus.ObjectPath("/org/freedesktop/secrets")
+	secretsInterface  = "org.freedesktop.Secret.Service"
+	collectionIface   = "org.freedesktop.Secret.Collection"
+	itemIface         = "org.freedesktop.Secret.Item"
+	promptIface       = "org.freedesktop.Secret.Prompt"
+	secretApplication = "mangahub-desktop"
+
+	// promptTimeout bounds how long we wait for the user to unlock the keyring
+	promptTimeout = 2 * time.Minute
+)
+
+// secret is the Secret Service (oayays) secret struct
+type secret struct {
+	Session     dbus.ObjectPath
+	Parameters  []byte
+	Value       []byte
+	ContentType string
+}
+
+// SecretService stores secrets in the desktop keyring (GNOME Keyring,
+// KWallet...) through the freedesktop Secret Service D-Bus API. Items are
+// tagged with application=mangahub-desktop and key=<key>.
+type SecretService struct {
+	mu         sync.Mutex
+	conn       *dbus.Conn
+	session    dbus.ObjectPath
+	collection dbus.ObjectPath
+}
+
+// NewSecretService connects to the session bus and opens a plain session on
+// the default collection
+func NewSecretService() (Store, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, err
+	}
+
+	service := conn.Object(secretsService, secretsPath)
+
+	// Secrets travel over the local session bus only, so no transport
+	// encryption is negotiated
+	var output dbus.Variant
+	var session dbus.ObjectPath
+	if err := service.Call(secretsInterface+".OpenSession", 0, "plain", dbus.MakeVariant("")).Store(&output, &session); err != nil {
+		return nil, fmt.Errorf("failed to open Secret Service session: %w", err)
+	}
+
+	var collection dbus.ObjectPath
+	if err := service.Call(secretsInterface+".ReadAlias", 0, "default").Store(&collection); err != nil {
+		return nil, fmt.Errorf("failed to find default keyring: %w", err)
+	}
+	if collection == "/" {
+		return nil, fmt.Errorf("no default keyring")
+	}
+
+	return &SecretService{conn: conn, session: session, collection: collection}, nil
+}
+
+func (s *SecretService) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, err := s.find(key)
+	if err != nil {
+		return "", err
+	}
+	if item == "" {
+		return "", ErrNotFound
+	}
+
+	if err := s.unlock(item); err != nil {
+		return "", err
+	}
+
+	var sec secret
+	if err := s.conn.Object(secretsService, item).Call(itemIface+".GetSecret", 0, s.session).Store(&sec); err != nil {
+		return "", fmt.Errorf("failed to read secret: %w", err)
+	}
+	return string(sec.Value), nil
+}
+
+func (s *SecretService) Set(key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.unlock(s.collection); err != nil {
+		return err
+	}
+
+	properties := map[string]dbus.Variant{
+		itemIface + ".Label":      dbus.MakeVariant("MangaHub Desktop (" + key + ")"),
+		itemIface + ".Attributes": dbus.MakeVariant(attributes(key)),
+	}
+	sec := secret{
+		Session:     s.session,
+		Parameters:  []byte{},
+		Value:       []byte(value),
+		ContentType: "text/plain; charset=utf8",
+	}
+
+	var item, prompt dbus.ObjectPath
+	call := s.conn.Object(secretsService, s.collection).Call(collectionIface+".CreateItem", 0, properties, sec, true)
+	if err := call.Store(&item, &prompt); err != nil {
+		return fmt.Errorf("failed to store secret: %w", err)
+	}
+	return s.prompt(prompt)
+}
+
+func (s *SecretService) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, err := s.find(key)
+	if err != nil || item == "" {
+		return err
+	}
+
+	var prompt dbus.ObjectPath
+	if err := s.conn.Object(secretsService, item).Call(itemIface+".Delete", 0).Store(&prompt); err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	return s.prompt(prompt)
+}
+
+func (s *SecretService) Name() string {
+	return BackendSecretService
+}
+
+// find returns the item stored under key, or "" when there is none
+func (s *SecretService) find(key string) (dbus.ObjectPath, error) {
+	var items []dbus.ObjectPath
+	if err := s.conn.Object(secretsService, s.collection).Call(collectionIface+".SearchItems", 0, attributes(key)).Store(&items); err != nil {
+		return "", fmt.Errorf("failed to search keyring: %w", err)
+	}
+	if len(items) == 0 {
+		return "", nil
+	}
+	return items[0], nil
+}
+
+// unlock makes sure object is unlocked, asking the user if needed
+func (s *SecretService) unlock(object dbus.ObjectPath) error {
+	var unlocked []dbus.ObjectPath
+	var prompt dbus.ObjectPath
+	call := s.conn.Object(secretsService, secretsPath).Call(secretsInterface+".Unlock", 0, []dbus.ObjectPath{object})
+	if err := call.Store(&unlocked, &prompt); err != nil {
+		return fmt.Errorf("failed to unlock keyring: %w", err)
+	}
+	return s.prompt(prompt)
+}
+
+// prompt runs a Secret Service prompt and waits for it to complete
+func (s *SecretService) prompt(prompt dbus.ObjectPath) error {
+	if prompt == "" || prompt == "/" {
+		return nil
+	}
+
+	match := []dbus.MatchOption{
+		dbus.WithMatchObjectPath(prompt),
+		dbus.WithMatchInterface(promptIface),
+		dbus.WithMatchMember("Completed"),
+	}
+	if err := s.conn.AddMatchSignal(match...); err != nil {
+		return err
+	}
+	defer s.conn.RemoveMatchSignal(match...)
+
+	signals := make(chan *dbus.Signal, 1)
+	s.conn.Signal(signals)
+	defer s.conn.RemoveSignal(signals)
+
+	if err := s.conn.Object(secretsService, prompt).Call(promptIface+".Prompt", 0, "").Err; err != nil {
+		return fmt.Errorf("failed to show keyring prompt: %w", err)
+	}
+
+	timeout := time.After(promptTimeout)
+	for {
+		select {
+		case sig := <-signals:
+			if sig.Path != prompt || len(sig.Body) == 0 {
+				continue
+			}
+			if dismissed, _ := sig.Body[0].(bool); dismissed {
+				return fmt.Errorf("keyring prompt dismissed")
+			}
+			return nil
+		case <-timeout:
+			return fmt.Errorf("timed out waiting for keyring prompt")
+		}
+	}
+}
+
+func attributes(key string) map[string]string {
+	return map[string]string{
+		"application": secretApplication,
+		"key":         key,
+	}
+}
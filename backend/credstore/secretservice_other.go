@@ -0,0 +1,9 @@
+//go:build !linux
+
+package credstore
+
+import "fmt"
+
+func NewSecretService() (Store, error) {
+	return nil, fmt.Errorf("Secret Service is only available on Linux")
+}
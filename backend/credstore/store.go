@@ -0,0 +1,79 @@
+// Package credstore keeps secrets such as the bearer token out of plaintext
+// files: in the desktop keyring when one is available, otherwise in an
+// AES-GCM encrypted file.
+package credstore
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when no secret is stored under the key
+var ErrNotFound = errors.New("credential not found")
+
+// Store is a key/value store for secrets. Delete of a missing key is not an error.
+type Store interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+	// Name identifies the backend in logs and settings
+	Name() string
+}
+
+// Backends selectable with MANGAHUB_CREDSTORE
+const (
+	BackendSecretService = "secret-service"
+	BackendFile          = "file"
+	BackendMemory        = "memory"
+)
+
+// Open picks the credential store for dir. MANGAHUB_CREDSTORE forces a
+// backend; otherwise the Secret Service is used when reachable and the
+// encrypted file is the fallback. MANGAHUB_CREDSTORE_PASSPHRASE derives the
+// file key from a passphrase instead of the machine ID.
+func Open(dir string) (Store, error) {
+	passphrase := os.Getenv("MANGAHUB_CREDSTORE_PASSPHRASE")
+
+	switch backend := strings.ToLower(os.Getenv("MANGAHUB_CREDSTORE")); backend {
+	case BackendSecretService:
+		return NewSecretService()
+	case BackendFile:
+		return NewFile(dir, passphrase)
+	case BackendMemory:
+		return NewMemory(), nil
+	case "":
+	default:
+		return nil, fmt.Errorf("unknown credential store %q", backend)
+	}
+
+	if passphrase == "" {
+		if store, err := NewSecretService(); err == nil {
+			return store, nil
+		} else {
+			log.Printf("🔐 Secret Service unavailable, using encrypted file: %v", err)
+		}
+	}
+	return NewFile(dir, passphrase)
+}
+
+// MigrateFile moves a plaintext secret from path into the store under key
+// and deletes the file. A missing file is not an error.
+func MigrateFile(store Store, key, path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if value := strings.TrimSpace(string(data)); value != "" {
+		if err := store.Set(key, value); err != nil {
+			return err
+		}
+	}
+	return os.Remove(path)
+}
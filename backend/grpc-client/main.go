@@ -4,23 +4,76 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
+	"strconv"
+	"sync"
 	"time"
 
 	pb "mangahub-desktop/backend/grpc-client/manga"
-	"mangahub-desktop/backend/utils"
+	"mangahub-desktop/backend/transport"
 
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/status"
 )
 
+var (
+	addrMu sync.Mutex
+	// grpcHost is the host of the applied profile
+	grpcHost = "localhost"
+	// grpcPort is the port of the MangaHub gRPC service
+	grpcPort = 9092
+)
+
+// SetHost changes the host used to reach the gRPC service
+func SetHost(host string) {
+	addrMu.Lock()
+	defer addrMu.Unlock()
+	if host != "" {
+		grpcHost = host
+	}
+}
+
+// SetPort changes the port used to reach the gRPC service
+func SetPort(port int) {
+	addrMu.Lock()
+	defer addrMu.Unlock()
+	if port > 0 {
+		grpcPort = port
+	}
+}
+
+// Address returns the host:port of the gRPC service for the applied profile
+func Address() string {
+	addrMu.Lock()
+	defer addrMu.Unlock()
+	return net.JoinHostPort(grpcHost, strconv.Itoa(grpcPort))
+}
+
+// statusError keeps the server's message as the error text while still
+// exposing the gRPC status code to status.Code
+type statusError struct {
+	st *status.Status
+}
+
+func (e *statusError) Error() string {
+	return e.st.Message()
+}
+
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.st
+}
+
+func statusErr(err error) error {
+	st, _ := status.FromError(err)
+	return &statusError{st: st}
+}
+
 func NewMangaClient() (pb.MangaServiceClient, func(), error) {
 
-	serverAddr, _ := utils.LoadServerIPAddr()
-	grpcAddress := fmt.Sprintf("%s:9092", serverAddr)
+	grpcAddress := Address()
 	conn, err := grpc.NewClient(
-		grpcAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		transport.GRPCTarget(grpcAddress),
+		transport.GRPCDialOptions()...,
 	)
 	if err != nil {
 		return nil, nil, err
@@ -39,10 +92,9 @@ func newContext() (context.Context, context.CancelFunc) {
 
 func StartGRPCClientServer() {
 	// Connect to server
-	serverAddr, _ := utils.LoadServerIPAddr()
-	grpcAddress := fmt.Sprintf("%s:9092", serverAddr)
-	conn, err := grpc.NewClient(grpcAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	grpcAddress := Address()
+	conn, err := grpc.NewClient(transport.GRPCTarget(grpcAddress),
+		transport.GRPCDialOptions()...)
 	if err != nil {
 		log.Fatalf("Failed to connect: %v", err)
 	}
@@ -65,8 +117,7 @@ func GetMangaByID(mangaID string) (map[string]string, error) {
 		MangaId: mangaID,
 	})
 	if err != nil {
-		st, _ := status.FromError(err)
-		return nil, fmt.Errorf(st.Message())
+		return nil, statusErr(err)
 	}
 
 	result := map[string]string{
@@ -78,7 +129,10 @@ func GetMangaByID(mangaID string) (map[string]string, error) {
 
 	return result, nil
 }
-func UpdateProgress(mangaID string, chapter int64) error {
+
+// UpdateProgress records progress for userID, which callers take from the
+// server-confirmed identity
+func UpdateProgress(userID int64, mangaID string, chapter int64) error {
 	client, cleanup, err := NewMangaClient()
 	if err != nil {
 		return err
@@ -86,13 +140,6 @@ func UpdateProgress(mangaID string, chapter int64) error {
 	defer cleanup()
 
 	ctx, cancel := newContext()
-	token, _ := utils.LoadToken()
-	claims, err := utils.ValidateToken(token)
-	if err != nil {
-		return err
-	}
-	userID := claims.UserId
-
 	defer cancel()
 
 	resp, err := client.UpdateProgress(ctx, &pb.UpdateProgressRequest{
@@ -101,8 +148,7 @@ func UpdateProgress(mangaID string, chapter int64) error {
 		Chapter: chapter,
 	})
 	if err != nil {
-		st, _ := status.FromError(err)
-		return fmt.Errorf(st.Message())
+		return statusErr(err)
 	}
 
 	if resp.Success {
@@ -132,8 +178,7 @@ func SearchManga(keyword string, page int32, pageSize int32) ([]map[string]strin
 		PageSize: pageSize,
 	})
 	if err != nil {
-		st, _ := status.FromError(err)
-		return nil, 0, fmt.Errorf(st.Message())
+		return nil, 0, statusErr(err)
 	}
 
 	results := make([]map[string]string, 0, len(resp.Results))
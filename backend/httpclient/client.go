@@ -0,0 +1,237 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"mangahub-desktop/backend/utils"
+)
+
+// Version is the app version reported in the User-Agent.
+// Set at build time with -ldflags "-X mangahub-desktop/backend/httpclient.Version=1.2.3"
+var Version = "dev"
+
+const (
+	DefaultTimeout    = 15 * time.Second
+	DefaultMaxRetries = 3
+	DefaultBaseDelay  = 300 * time.Millisecond
+	DefaultMaxDelay   = 5 * time.Second
+)
+
+// Client is the shared REST client used by every HTTP service
+type Client struct {
+	HTTP       *http.Client
+	Timeout    time.Duration // per attempt
+	MaxRetries int           // extra attempts for idempotent requests
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	UserAgent  string
+
+	OnUnauthorized func() // Called when an authenticated request gets a 401
+}
+
+// New creates a client sending requests through transport (nil for the default)
+func New(transport http.RoundTripper) *Client {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Client{
+		HTTP:       &http.Client{Transport: transport},
+		Timeout:    DefaultTimeout,
+		MaxRetries: DefaultMaxRetries,
+		BaseDelay:  DefaultBaseDelay,
+		MaxDelay:   DefaultMaxDelay,
+		UserAgent:  fmt.Sprintf("MangaHub-Desktop/%s (%s; %s)", Version, runtime.GOOS, runtime.GOARCH),
+	}
+}
+
+// NewRequest builds a request, JSON-encoding body when it is not nil
+func (c *Client) NewRequest(method, url string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		// bytes.Reader lets http.NewRequest set GetBody, so retries can replay it
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// NewAuthRequest is NewRequest with the stored bearer token attached
+func (c *Client) NewAuthRequest(method, url string, body interface{}) (*http.Request, error) {
+	jwt, _ := utils.LoadToken()
+	if jwt == "" {
+		return nil, fmt.Errorf("not logged in")
+	}
+
+	req, err := c.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	return req, nil
+}
+
+type credentialCheckKey struct{}
+
+// WithCredentialCheck marks a request that re-confirms the user's password:
+// a 401 then means the password was wrong, not that the session ended
+func WithCredentialCheck(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), credentialCheckKey{}, true))
+}
+
+// Do sends the request with a per-attempt deadline. Idempotent requests are
+// retried with exponential backoff and jitter on network errors and on
+// 429/502/503/504 responses.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	attempts := 1
+	if isIdempotent(req.Method) && (req.Body == nil || req.GetBody != nil) {
+		attempts += c.MaxRetries
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(req.Context(), attempt, lastErr); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.attempt(req, attempt)
+		if err != nil {
+			// The caller gave up - do not retry
+			if req.Context().Err() != nil {
+				return nil, networkError(err)
+			}
+			lastErr = networkError(err)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && req.Header.Get("Authorization") != "" &&
+			req.Context().Value(credentialCheckKey{}) == nil && c.OnUnauthorized != nil {
+			c.OnUnauthorized()
+		}
+
+		if attempt < attempts-1 && isRetryableStatus(resp.StatusCode) {
+			lastErr = &retryAfterError{status: resp.Status, after: retryAfter(resp)}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			continue
+		}
+		return resp, nil
+	}
+
+	return nil, lastErr
+}
+
+func (c *Client) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), c.Timeout)
+
+	r := req.Clone(ctx)
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	resp, err := c.HTTP.Do(r)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// Keep the deadline alive while the caller reads the body
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (c *Client) wait(ctx context.Context, attempt int, lastErr error) error {
+	delay := c.BaseDelay << (attempt - 1)
+	if delay > c.MaxDelay || delay <= 0 {
+		delay = c.MaxDelay
+	}
+	// Jitter: somewhere between half and the full backoff
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	if ra, ok := lastErr.(*retryAfterError); ok && ra.after > delay {
+		delay = ra.after
+		if delay > c.MaxDelay {
+			delay = c.MaxDelay
+		}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+type retryAfterError struct {
+	status string
+	after  time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return "server returned " + e.status
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
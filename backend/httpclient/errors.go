@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// CodeNetwork marks failures where no HTTP response was received
+const CodeNetwork = "network_error"
+
+// APIError is a failed REST call. It is returned to the frontend as a
+// structured object (see ErrorFormatter) instead of a raw response body.
+type APIError struct {
+	Status    int    `json:"status"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+func (e *APIError) Error() string {
+	if e.Status == 0 {
+		return e.Message
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("%s (%d %s)", e.Message, e.Status, e.Code)
+	}
+	return fmt.Sprintf("%s (%d)", e.Message, e.Status)
+}
+
+// errorBody covers the error shapes the server sends:
+// {"error": "..."}, {"message": "..."} and {"error": {"code": "...", "message": "..."}}
+type errorBody struct {
+	Error     json.RawMessage `json:"error"`
+	Message   string          `json:"message"`
+	Code      string          `json:"code"`
+	RequestID string          `json:"request_id"`
+}
+
+// ParseError reads an error response into an APIError
+func ParseError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		Status:    resp.StatusCode,
+		RequestID: resp.Header.Get("X-Request-ID"),
+		Retryable: isRetryableStatus(resp.StatusCode) || resp.StatusCode == http.StatusRequestTimeout,
+	}
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	text := strings.TrimSpace(string(raw))
+
+	var body errorBody
+	if err := json.Unmarshal(raw, &body); err == nil {
+		apiErr.Message = body.Message
+		apiErr.Code = body.Code
+		if body.RequestID != "" {
+			apiErr.RequestID = body.RequestID
+		}
+
+		var msg string
+		var nested errorBody
+		switch {
+		case json.Unmarshal(body.Error, &msg) == nil:
+			if apiErr.Message == "" {
+				apiErr.Message = msg
+			} else if apiErr.Code == "" {
+				apiErr.Code = msg
+			}
+		case json.Unmarshal(body.Error, &nested) == nil:
+			if nested.Message != "" {
+				apiErr.Message = nested.Message
+			}
+			if nested.Code != "" {
+				apiErr.Code = nested.Code
+			}
+		}
+	} else if text != "" && len(text) < 512 {
+		apiErr.Message = text
+	}
+
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
+
+func networkError(err error) *APIError {
+	return &APIError{
+		Code:      CodeNetwork,
+		Message:   err.Error(),
+		Retryable: true,
+	}
+}
+
+// IsNetworkError reports whether err means the server could not be reached
+func IsNetworkError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == CodeNetwork
+}
+
+// IsUnauthorized reports whether err is a 401 from the server
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized
+}
+
+// StructuredError is implemented by errors that reach the frontend as an
+// object rather than a message, e.g. validation.Error
+type StructuredError interface {
+	error
+	Structured() any
+}
+
+// ErrorFormatter passes APIErrors and StructuredErrors to the frontend as
+// objects and every other error as its message
+func ErrorFormatter(err error) any {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	var structured StructuredError
+	if errors.As(err, &structured) {
+		return structured.Structured()
+	}
+	return err.Error()
+}
@@ -0,0 +1,163 @@
+// Package mal reads and writes MyAnimeList manga list exports
+// (Profile > Export > Manga List), plain or gzipped.
+package mal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Statuses used by MAL exports
+const (
+	StatusReading    = "Reading"
+	StatusCompleted  = "Completed"
+	StatusOnHold     = "On-Hold"
+	StatusDropped    = "Dropped"
+	StatusPlanToRead = "Plan to Read"
+)
+
+// Export is a whole <myanimelist> document
+type Export struct {
+	XMLName xml.Name `xml:"myanimelist"`
+	Info    Info     `xml:"myinfo"`
+	Manga   []Entry  `xml:"manga"`
+}
+
+// ExportTypeManga marks a manga list in <user_export_type>
+const ExportTypeManga = 2
+
+// noDate is how exports write a date that is not set
+const noDate = "0000-00-00"
+
+// Info is the <myinfo> header
+type Info struct {
+	UserID          int64  `xml:"user_id"`
+	UserName        string `xml:"user_name"`
+	ExportType      int    `xml:"user_export_type"`
+	TotalManga      int    `xml:"user_total_manga"`
+	TotalReading    int    `xml:"user_total_reading"`
+	TotalCompleted  int    `xml:"user_total_completed"`
+	TotalOnHold     int    `xml:"user_total_onhold"`
+	TotalDropped    int    `xml:"user_total_dropped"`
+	TotalPlanToRead int    `xml:"user_total_plantoread"`
+}
+
+// Entry is one <manga> element
+type Entry struct {
+	MangaDBID      int64  `xml:"manga_mangadb_id" json:"mal_id"`
+	Title          string `xml:"manga_title" json:"title"`
+	Volumes        int    `xml:"manga_volumes" json:"volumes"`
+	Chapters       int    `xml:"manga_chapters" json:"chapters"`
+	ReadVolumes    int    `xml:"my_read_volumes" json:"read_volumes"`
+	ReadChapters   int    `xml:"my_read_chapters" json:"read_chapters"`
+	StartDate      string `xml:"my_start_date" json:"start_date"`
+	FinishDate     string `xml:"my_finish_date" json:"finish_date"`
+	Score          int    `xml:"my_score" json:"score"`
+	Status         string `xml:"my_status" json:"status"`
+	Comments       string `xml:"my_comments" json:"comments"`
+	TimesRead      int    `xml:"my_times_read" json:"times_read"`
+	Tags           string `xml:"my_tags" json:"tags"`
+	UpdateOnImport int    `xml:"update_on_import" json:"-"`
+}
+
+// Parse reads an export, decompressing it first if it is gzipped
+func Parse(r io.Reader) (*Export, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	var export Export
+	if err := xml.NewDecoder(r).Decode(&export); err != nil {
+		return nil, fmt.Errorf("not a MyAnimeList export: %w", err)
+	}
+	if export.Info.ExportType != 0 && export.Info.ExportType != ExportTypeManga {
+		return nil, fmt.Errorf("not a manga list export (export type %d)", export.Info.ExportType)
+	}
+	for i := range export.Manga {
+		export.Manga[i].Title = strings.TrimSpace(export.Manga[i].Title)
+		export.Manga[i].Status = strings.TrimSpace(export.Manga[i].Status)
+	}
+	return &export, nil
+}
+
+// LibraryStatus maps a MAL status to a MangaHub list. On-hold titles are kept
+// with the ones being read; dropped titles have no list and report false.
+func LibraryStatus(status string) (string, bool) {
+	switch strings.ToLower(status) {
+	case "reading", "1", "on-hold", "3":
+		return "reading", true
+	case "completed", "2":
+		return "completed", true
+	case "plan to read", "6":
+		return "plan_to_read", true
+	}
+	return "", false
+}
+
+// MALStatus maps a MangaHub list to the status MAL writes for it
+func MALStatus(status string) string {
+	switch status {
+	case "completed":
+		return StatusCompleted
+	case "plan_to_read":
+		return StatusPlanToRead
+	}
+	return StatusReading
+}
+
+// Write encodes export in MAL's format, filling in the header totals and
+// the placeholders MAL uses for unset dates. Entries without a
+// MangaDBID are matched by title by the importing site, if it can.
+func Write(w io.Writer, export *Export) error {
+	export.Info.ExportType = ExportTypeManga
+	export.Info.TotalManga = len(export.Manga)
+	export.Info.TotalReading, export.Info.TotalCompleted, export.Info.TotalOnHold = 0, 0, 0
+	export.Info.TotalDropped, export.Info.TotalPlanToRead = 0, 0
+
+	for i := range export.Manga {
+		e := &export.Manga[i]
+		switch e.Status {
+		case StatusReading:
+			export.Info.TotalReading++
+		case StatusCompleted:
+			export.Info.TotalCompleted++
+		case StatusOnHold:
+			export.Info.TotalOnHold++
+		case StatusDropped:
+			export.Info.TotalDropped++
+		case StatusPlanToRead:
+			export.Info.TotalPlanToRead++
+		}
+		if e.StartDate == "" {
+			e.StartDate = noDate
+		}
+		if e.FinishDate == "" {
+			e.FinishDate = noDate
+		}
+		e.UpdateOnImport = 1
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "\t")
+	if err := enc.Encode(export); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,172 @@
+package mal
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const mangaExport = `<?xml version="1.0" encoding="UTF-8" ?>
+<myanimelist>
+	<myinfo>
+		<user_id>42</user_id>
+		<user_name>reader</user_name>
+		<user_export_type>2</user_export_type>
+		<user_total_manga>2</user_total_manga>
+	</myinfo>
+	<manga>
+		<manga_mangadb_id>2</manga_mangadb_id>
+		<manga_title><![CDATA[ Berserk ]]></manga_title>
+		<manga_volumes>0</manga_volumes>
+		<manga_chapters>0</manga_chapters>
+		<my_read_volumes>41</my_read_volumes>
+		<my_read_chapters>364</my_read_chapters>
+		<my_start_date>2019-04-01</my_start_date>
+		<my_finish_date>0000-00-00</my_finish_date>
+		<my_score>10</my_score>
+		<my_status>Reading</my_status>
+		<my_comments><![CDATA[Eclipse arc]]></my_comments>
+	</manga>
+	<manga>
+		<manga_mangadb_id>13</manga_mangadb_id>
+		<manga_title><![CDATA[One Piece]]></manga_title>
+		<my_read_chapters>0</my_read_chapters>
+		<my_status>Plan to Read</my_status>
+	</manga>
+</myanimelist>
+`
+
+const animeExport = `<?xml version="1.0" encoding="UTF-8" ?>
+<myanimelist>
+	<myinfo>
+		<user_name>reader</user_name>
+		<user_export_type>1</user_export_type>
+	</myinfo>
+</myanimelist>
+`
+
+func gzipped(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"plain", []byte(mangaExport)},
+		{"gzipped", gzipped(t, mangaExport)},
+	}
+	for _, tt := range tests {
+		export, err := Parse(bytes.NewReader(tt.data))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if export.Info.UserName != "reader" || len(export.Manga) != 2 {
+			t.Fatalf("%s: got %+v", tt.name, export)
+		}
+		berserk := export.Manga[0]
+		if berserk.Title != "Berserk" {
+			t.Errorf("%s: title not trimmed: %q", tt.name, berserk.Title)
+		}
+		if berserk.ReadChapters != 364 || berserk.ReadVolumes != 41 || berserk.Status != StatusReading {
+			t.Errorf("%s: got %+v", tt.name, berserk)
+		}
+		if berserk.Comments != "Eclipse arc" || berserk.StartDate != "2019-04-01" {
+			t.Errorf("%s: got %+v", tt.name, berserk)
+		}
+		if export.Manga[1].Status != StatusPlanToRead {
+			t.Errorf("%s: got status %q", tt.name, export.Manga[1].Status)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"anime export", []byte(animeExport)},
+		{"gzipped anime export", gzipped(t, animeExport)},
+		{"not XML", []byte("title,status\nBerserk,Reading\n")},
+		{"broken gzip", []byte{0x1f, 0x8b, 0x00}},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(bytes.NewReader(tt.data)); err == nil {
+			t.Errorf("%s: Parse succeeded", tt.name)
+		}
+	}
+}
+
+func TestLibraryStatus(t *testing.T) {
+	tests := []struct {
+		status string
+		want   string
+		ok     bool
+	}{
+		{StatusReading, "reading", true},
+		{StatusCompleted, "completed", true},
+		{StatusOnHold, "reading", true},
+		{StatusDropped, "", false},
+		{StatusPlanToRead, "plan_to_read", true},
+		{"reading", "reading", true},
+		{"PLAN TO READ", "plan_to_read", true},
+		{"1", "reading", true},
+		{"2", "completed", true},
+		{"3", "reading", true},
+		{"4", "", false},
+		{"6", "plan_to_read", true},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := LibraryStatus(tt.status)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("LibraryStatus(%q) = %q, %v; want %q, %v", tt.status, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	export := &Export{
+		Info: Info{UserName: "reader"},
+		Manga: []Entry{
+			{Title: "Berserk", ReadChapters: 364, ReadVolumes: 41, Status: MALStatus("reading"), StartDate: "2019-04-01", Comments: "Eclipse & beyond <3"},
+			{Title: "Monster", Chapters: 162, ReadChapters: 162, Status: MALStatus("completed")},
+			{Title: "進撃の巨人", Status: MALStatus("plan_to_read")},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, export); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "<?xml") {
+		t.Errorf("missing XML header: %.40q", buf.String())
+	}
+
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Info{UserName: "reader", ExportType: ExportTypeManga, TotalManga: 3, TotalReading: 1, TotalCompleted: 1, TotalPlanToRead: 1}
+	if parsed.Info != want {
+		t.Errorf("info = %+v, want %+v", parsed.Info, want)
+	}
+	if !reflect.DeepEqual(parsed.Manga, export.Manga) {
+		t.Errorf("entries changed in the round trip:\n got %+v\nwant %+v", parsed.Manga, export.Manga)
+	}
+	if parsed.Manga[1].StartDate != noDate || parsed.Manga[1].UpdateOnImport != 1 {
+		t.Errorf("unset date or update flag not written: %+v", parsed.Manga[1])
+	}
+}
@@ -0,0 +1,143 @@
+// Package match scores how likely two manga titles name the same series,
+// for importers that only know a title
+package match
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// Confident is the score from which a best candidate is taken as is
+	Confident = 0.9
+	// Margin is how far the best candidate must lead the next one to be
+	// taken without review
+	Margin = 0.1
+	// Plausible is the lowest score still offered for review
+	Plausible = 0.5
+)
+
+// Normalize folds case, accents and punctuation so "Kaguya-sama: Love Is
+// War" and "kaguya sama love is war" compare equal
+func Normalize(title string) string {
+	var b strings.Builder
+	space := true
+	for _, r := range norm.NFKD.String(strings.ToLower(title)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Combining accent left by NFKD
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+			space = false
+		case !space:
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Similarity returns 0..1 for two titles: the Sørensen-Dice coefficient of
+// their character bigrams after Normalize
+func Similarity(a, b string) float64 {
+	a, b = Normalize(a), Normalize(b)
+	if a == b {
+		if a == "" {
+			return 0
+		}
+		return 1
+	}
+
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+	counts := map[string]int{}
+	for _, g := range ba {
+		counts[g]++
+	}
+	shared := 0
+	for _, g := range bb {
+		if counts[g] > 0 {
+			counts[g]--
+			shared++
+		}
+	}
+	return 2 * float64(shared) / float64(len(ba)+len(bb))
+}
+
+func bigrams(s string) []string {
+	runes := []rune(s)
+	if len(runes) < 2 {
+		return []string{s}
+	}
+	grams := make([]string, 0, len(runes)-1)
+	for i := 0; i < len(runes)-1; i++ {
+		grams = append(grams, string(runes[i:i+2]))
+	}
+	return grams
+}
+
+const (
+	// authorBonus is added when the author is known on both sides and agrees
+	authorBonus = 0.1
+	// sameAuthor is the similarity from which two author names agree
+	sameAuthor = 0.8
+)
+
+// Candidate is a catalog entry compared against the wanted series
+type Candidate struct {
+	Title  string
+	Author string
+}
+
+// Scored is a candidate with its similarity to the wanted series
+type Scored struct {
+	Index int
+	Score float64
+}
+
+// Rank scores candidates against title, best first. author may be empty;
+// when given, candidates by the same author are preferred.
+func Rank(title, author string, candidates []Candidate) []Scored {
+	scored := make([]Scored, len(candidates))
+	for i, c := range candidates {
+		score := Similarity(title, c.Title)
+		if author != "" && c.Author != "" && Similarity(author, c.Author) >= sameAuthor {
+			score = math.Min(1, score+authorBonus)
+		}
+		scored[i] = Scored{Index: i, Score: score}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	return scored
+}
+
+// Decisive reports whether the best of ranked is clear enough to take
+// without asking
+func Decisive(ranked []Scored) bool {
+	if len(ranked) == 0 || ranked[0].Score < Confident {
+		return false
+	}
+	if len(ranked) == 1 || ranked[0].Score-ranked[1].Score >= Margin {
+		return true
+	}
+	// An exact title wins over near misses like its sequels
+	return ranked[0].Score == 1 && ranked[1].Score < 1
+}
+
+// SearchQuery shortens a title for catalog search: subtitles after ':' or
+// ' - ' are dropped since catalogs often list the series under its main title
+func SearchQuery(title string) string {
+	for _, sep := range []string{":", " - ", " – "} {
+		if i := strings.Index(title, sep); i > 0 {
+			return strings.TrimSpace(title[:i])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package match
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Kaguya-sama: Love Is War", "kaguya sama love is war"},
+		{"  kaguya sama   love is war ", "kaguya sama love is war"},
+		{"Pokémon Adventures", "pokemon adventures"},
+		{"ＯＮＥ ＰＩＥＣＥ", "one piece"},
+		{"Dr. STONE!!", "dr stone"},
+		{"進撃の巨人", "進撃の巨人"},
+		{"?!", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		min, max float64
+	}{
+		{"Kaguya-sama: Love Is War", "kaguya sama love is war", 1, 1},
+		{"One Piece", "One Piece", 1, 1},
+		{"Berserk", "Berserk of Gluttony", 0.5, 0.9},
+		{"Naruto", "Bleach", 0, 0.1},
+		{"", "", 0, 0},
+		{"!!", "Naruto", 0, 0},
+	}
+	for _, tt := range tests {
+		got := Similarity(tt.a, tt.b)
+		if got < tt.min || got > tt.max {
+			t.Errorf("Similarity(%q, %q) = %.3f, want %.2f..%.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+		if back := Similarity(tt.b, tt.a); back != got {
+			t.Errorf("Similarity(%q, %q) = %.3f but reversed %.3f", tt.a, tt.b, got, back)
+		}
+	}
+}
+
+func TestRankAuthorBonus(t *testing.T) {
+	candidates := []Candidate{
+		{Title: "Monster", Author: "Someone Else"},
+		{Title: "Monster", Author: "Naoki Urasawa"},
+	}
+	ranked := Rank("Monster", "Urasawa Naoki", candidates)
+	if ranked[0].Score != 1 || ranked[1].Score != 1 {
+		t.Fatalf("exact titles should score 1: %+v", ranked)
+	}
+
+	ranked = Rank("Monstr", "Naoki Urasawa", candidates)
+	if ranked[0].Index != 1 {
+		t.Errorf("same author should rank first: %+v", ranked)
+	}
+}
+
+func TestDecisive(t *testing.T) {
+	rank := func(title string, titles ...string) []Scored {
+		candidates := make([]Candidate, len(titles))
+		for i, c := range titles {
+			candidates[i] = Candidate{Title: c}
+		}
+		return Rank(title, "", candidates)
+	}
+
+	tests := []struct {
+		name   string
+		ranked []Scored
+		want   bool
+	}{
+		{"no candidates", rank("Attack on Titan"), false},
+		{"single exact", rank("Attack on Titan", "Attack on Titan"), true},
+		{"exact over sequel", rank("Attack on Titan", "Attack on Titan 2", "Attack on Titan"), true},
+		{"sequels only", rank("Attack on Titan", "Attack on Titan 2", "Attack on Titan 3"), false},
+		{"weak match", rank("Attack on Titan", "Titan Attack"), false},
+		{"two exact", rank("Monster", "Monster", "Monster"), false},
+	}
+	for _, tt := range tests {
+		if got := Decisive(tt.ranked); got != tt.want {
+			t.Errorf("%s: Decisive(%+v) = %v, want %v", tt.name, tt.ranked, got, tt.want)
+		}
+	}
+}
+
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Kaguya-sama: Love Is War", "Kaguya-sama"},
+		{"Fullmetal Alchemist - Brotherhood", "Fullmetal Alchemist"},
+		{"Vinland Saga – Prologue", "Vinland Saga"},
+		{"One Piece", ""},
+		{": Leading colon", ""},
+	}
+	for _, tt := range tests {
+		if got := SearchQuery(tt.in); got != tt.want {
+			t.Errorf("SearchQuery(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
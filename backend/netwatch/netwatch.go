@@ -0,0 +1,156 @@
+package netwatch
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// pollInterval is used when kernel notifications are unavailable
+	pollInterval = 5 * time.Second
+	// backstopInterval still polls alongside kernel notifications, which
+	// catches resume-from-sleep where no address event is delivered
+	backstopInterval = 30 * time.Second
+	// settleDelay lets a burst of interface events finish before comparing
+	settleDelay = 2 * time.Second
+)
+
+// Change describes a detected network change
+type Change struct {
+	Reason    string   `json:"reason"` // address | resume
+	Addresses []string `json:"addresses"`
+}
+
+// Watcher reports local address/interface changes and resumes from sleep
+type Watcher struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func New() *Watcher {
+	return &Watcher{}
+}
+
+// Start watches until ctx is done or Stop is called. onChange runs on the
+// watcher goroutine.
+func (w *Watcher) Start(ctx context.Context, onChange func(Change)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		return
+	}
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	events, err := subscribe(ctx)
+	interval := backstopInterval
+	if err != nil {
+		fmt.Printf("⚠️ Network notifications unavailable (%v), polling every %s\n", err, pollInterval)
+		interval = pollInterval
+	}
+
+	go w.run(ctx, events, interval, onChange)
+}
+
+// Stop ends watching
+func (w *Watcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cancel != nil {
+		w.cancel()
+		w.cancel = nil
+	}
+}
+
+func (w *Watcher) run(ctx context.Context, events <-chan struct{}, interval time.Duration, onChange func(Change)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := snapshot()
+	lastTick := time.Now()
+
+	for {
+		reason := "address"
+
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-events:
+			if !ok {
+				// Kernel notifications stopped - fall back to polling
+				events = nil
+				ticker.Reset(pollInterval)
+				interval = pollInterval
+				continue
+			}
+			// Wait for the burst to settle, draining further events
+			timer := time.NewTimer(settleDelay)
+		settle:
+			for {
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case _, ok := <-events:
+					if !ok {
+						// Closed mid-burst - finish settling, then poll
+						events = nil
+						ticker.Reset(pollInterval)
+						interval = pollInterval
+					}
+				case <-timer.C:
+					break settle
+				}
+			}
+		case now := <-ticker.C:
+			// Monotonic time stops while suspended; a wall clock jump far
+			// beyond the tick interval means we just woke up
+			gap := now.Round(0).Sub(lastTick.Round(0))
+			lastTick = now
+			if gap > 3*interval {
+				reason = "resume"
+			}
+		}
+
+		current := snapshot()
+		if reason != "resume" && equal(current, last) {
+			continue
+		}
+		last = current
+		onChange(Change{Reason: reason, Addresses: current})
+	}
+}
+
+// snapshot lists "iface ip/prefix" for every up, non-loopback interface
+func snapshot() []string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var result []string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			result = append(result, iface.Name+" "+a.String())
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func equal(a, b []string) bool {
+	return strings.Join(a, ",") == strings.Join(b, ",")
+}
@@ -0,0 +1,83 @@
+package probe
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"mangahub-desktop/backend/utils"
+)
+
+const (
+	// historySize is how many results are kept per server
+	historySize = 10
+	// minSamples is how many results are needed before a server can be demoted
+	minSamples = 3
+	// flakyThreshold is the success ratio below which a server is demoted
+	flakyThreshold = 0.5
+)
+
+var historyMu sync.Mutex
+
+func historyPath() string {
+	return filepath.Join(utils.ConfigDir(), "probe_history.json")
+}
+
+func loadHistory() map[string][]Result {
+	history := map[string][]Result{}
+	data, err := os.ReadFile(historyPath())
+	if err != nil {
+		return history
+	}
+	json.Unmarshal(data, &history)
+	return history
+}
+
+// Record appends a probe result to the server's history
+func Record(result Result) error {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	history := loadHistory()
+	results := append(history[result.Addr], result)
+	if len(results) > historySize {
+		results = results[len(results)-historySize:]
+	}
+	history[result.Addr] = results
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	os.MkdirAll(utils.ConfigDir(), 0700)
+	return os.WriteFile(historyPath(), data, 0600)
+}
+
+// History returns the recorded results for a server, oldest first
+func History(addr string) []Result {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	return loadHistory()[addr]
+}
+
+// Reliability returns the share of successful probes for a server,
+// or 1 when it has never been probed
+func Reliability(addr string) float64 {
+	results := History(addr)
+	if len(results) == 0 {
+		return 1
+	}
+	ok := 0
+	for _, r := range results {
+		if r.OK() {
+			ok++
+		}
+	}
+	return float64(ok) / float64(len(results))
+}
+
+// IsFlaky reports whether a server failed often enough to be demoted
+func IsFlaky(addr string) bool {
+	return len(History(addr)) >= minSamples && Reliability(addr) < flakyThreshold
+}
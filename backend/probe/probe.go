@@ -0,0 +1,83 @@
+package probe
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/transport"
+)
+
+// Result is the outcome of probing one server
+type Result struct {
+	Addr          string    `json:"addr"`
+	HTTPOK        bool      `json:"http_ok"`
+	SyncOK        bool      `json:"sync_ok"`
+	HTTPLatencyMs int64     `json:"http_latency_ms"`
+	SyncLatencyMs int64     `json:"sync_latency_ms"`
+	Error         string    `json:"error,omitempty"`
+	At            time.Time `json:"at"`
+}
+
+// OK reports whether both the REST API and the TCP sync port answered
+func (r Result) OK() bool {
+	return r.HTTPOK && r.SyncOK
+}
+
+// Probe checks the HTTP health endpoint and TCP sync reachability in parallel.
+// Any HTTP answer below 500 counts as up: older servers have no /health route.
+func Probe(addr, httpBase, syncAddr string, timeout time.Duration) Result {
+	result := Result{Addr: addr, At: time.Now()}
+
+	var wg sync.WaitGroup
+	var httpErr, syncErr error
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		client := &http.Client{Timeout: timeout, Transport: transport.HTTPTransport()}
+		resp, err := client.Get(httpBase + "/health")
+		if err != nil {
+			httpErr = err
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			httpErr = &statusError{resp.Status}
+			return
+		}
+		result.HTTPOK = true
+		result.HTTPLatencyMs = time.Since(start).Milliseconds()
+	}()
+	go func() {
+		defer wg.Done()
+		start := time.Now()
+		conn, err := transport.DialTCP(syncAddr, timeout)
+		if err != nil {
+			syncErr = err
+			return
+		}
+		conn.Close()
+		result.SyncOK = true
+		result.SyncLatencyMs = time.Since(start).Milliseconds()
+	}()
+	wg.Wait()
+
+	switch {
+	case httpErr != nil:
+		result.Error = "http: " + httpErr.Error()
+	case syncErr != nil:
+		result.Error = "sync: " + syncErr.Error()
+	}
+
+	return result
+}
+
+type statusError struct {
+	status string
+}
+
+func (e *statusError) Error() string {
+	return "health check returned " + e.status
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"mangahub-desktop/backend/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// AccountService keeps several logged-in accounts and switches between them
+type AccountService struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	identity *IdentityService
+	session  *SessionService
+	chat     *ChatService
+	sync     *SyncService
+	notify   *NotifyService
+
+	OnSwitch func() error // Callback to bring services up for the new account
+}
+
+func NewAccountService(
+	identity *IdentityService,
+	session *SessionService,
+	chat *ChatService,
+	sync *SyncService,
+	notify *NotifyService,
+) *AccountService {
+	return &AccountService{
+		identity: identity,
+		session:  session,
+		chat:     chat,
+		sync:     sync,
+		notify:   notify,
+	}
+}
+
+func (a *AccountService) SetContext(ctx context.Context) {
+	a.ctx = ctx
+}
+
+// ListAccounts returns the stored accounts, most recently used first
+func (a *AccountService) ListAccounts() ([]utils.Account, error) {
+	return utils.ListAccounts()
+}
+
+// GetActiveAccount returns the username currently in use
+func (a *AccountService) GetActiveAccount() string {
+	return utils.ActiveAccount()
+}
+
+// SwitchAccount tears down notifications, sync and chat, then brings them
+// back up under the other account, rejoining the chat room that was open
+func (a *AccountService) SwitchAccount(username string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if username == utils.ActiveAccount() {
+		return nil
+	}
+	if !utils.HasAccountToken(username) {
+		return fmt.Errorf("no stored login for %q, please log in", username)
+	}
+
+	room := a.chat.GetCurrentRoom()
+	utils.LogInfo(fmt.Sprintf("👤 Switching account to %s", username))
+
+	a.session.Stop()
+	// Leave while the old token is still the active one, or the server keeps
+	// sending its notifications to this device. Its subscriptions stay, so
+	// switching back brings them back.
+	a.notify.Leave()
+	a.sync.Stop()
+	a.chat.Disconnect()
+
+	account, err := utils.UseAccount(username)
+	if err != nil {
+		return err
+	}
+	a.identity.Invalidate()
+
+	if a.OnSwitch != nil {
+		if err := a.OnSwitch(); err != nil {
+			return err
+		}
+	}
+
+	if room != "" {
+		if err := a.chat.SwitchRoom(room); err != nil {
+			utils.LogError(fmt.Sprintf("Failed to rejoin chat room %s: %v", room, err))
+		}
+	}
+
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "account:switched", account)
+	}
+	return nil
+}
+
+// RemoveAccount forgets a stored account; the active one cannot be removed
+func (a *AccountService) RemoveAccount(username string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if username == utils.ActiveAccount() {
+		return fmt.Errorf("cannot remove the active account")
+	}
+	return utils.RemoveAccount(username)
+}
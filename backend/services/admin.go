@@ -1,21 +1,18 @@
 package services
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
 
-	"mangahub-desktop/backend/utils"
+	"mangahub-desktop/backend/httpclient"
 )
 
 type AdminService struct {
-	BaseURL string
+	base   *Endpoint
+	client *httpclient.Client
 }
 
-func NewAdminService(baseURL string) *AdminService {
-	return &AdminService{BaseURL: baseURL}
+func NewAdminService(base *Endpoint, client *httpclient.Client) *AdminService {
+	return &AdminService{base: base, client: client}
 }
 
 type UpdateMangaRequest struct {
@@ -39,11 +36,6 @@ type UpdateChapterRequest struct {
 
 // UpdateManga updates manga details (admin only)
 func (a *AdminService) UpdateManga(mangaID, title, author, artist, status string, genres []string, chapters, volumes, year, popularity, ranking int) error {
-	token, err := utils.LoadToken()
-	if err != nil {
-		return fmt.Errorf("not logged in")
-	}
-
 	req := UpdateMangaRequest{
 		ID: mangaID,
 	}
@@ -79,25 +71,19 @@ func (a *AdminService) UpdateManga(mangaID, title, author, artist, status string
 		req.Ranking = ranking
 	}
 
-	body, _ := json.Marshal(req)
-
-	httpReq, err := http.NewRequest("PUT", a.BaseURL+"/admin/manga", bytes.NewBuffer(body))
+	httpReq, err := a.client.NewAuthRequest("PUT", a.base.URL()+"/admin/manga", req)
 	if err != nil {
 		return err
 	}
 
-	httpReq.Header.Set("Authorization", "Bearer "+token)
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(httpReq)
+	resp, err := a.client.Do(httpReq)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed %s: %s", resp.Status, string(bodyBytes))
+		return httpclient.ParseError(resp)
 	}
 
 	return nil
@@ -105,35 +91,24 @@ func (a *AdminService) UpdateManga(mangaID, title, author, artist, status string
 
 // UpdateMangaChapter updates manga chapter release (admin only)
 func (a *AdminService) UpdateMangaChapter(mangaID string, chapter int) error {
-	token, err := utils.LoadToken()
-	if err != nil {
-		return fmt.Errorf("not logged in")
-	}
-
 	req := UpdateChapterRequest{
 		MangaID: mangaID,
 		Chapter: chapter,
 	}
 
-	body, _ := json.Marshal(req)
-
-	httpReq, err := http.NewRequest("PUT", a.BaseURL+"/admin/manga/chapter-release", bytes.NewBuffer(body))
+	httpReq, err := a.client.NewAuthRequest("PUT", a.base.URL()+"/admin/manga/chapter-release", req)
 	if err != nil {
 		return err
 	}
 
-	httpReq.Header.Set("Authorization", "Bearer "+token)
-	httpReq.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(httpReq)
+	resp, err := a.client.Do(httpReq)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed %s: %s", resp.Status, string(bodyBytes))
+		return httpclient.ParseError(resp)
 	}
 
 	return nil
@@ -1,23 +1,28 @@
 package services
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"time"
 
+	"mangahub-desktop/backend/httpclient"
 	"mangahub-desktop/backend/utils"
+	"mangahub-desktop/backend/validation"
 )
 
 type AuthService struct {
-	BaseURL        string
+	base           *Endpoint
 	OnLoginSuccess func() error // Callback to initialize services after login
+	OnLogout       func()       // Callback to tear services down while the token is still valid
+	client         *httpclient.Client
+	identity       *IdentityService
 }
 
-func NewAuthService(baseURL string) *AuthService {
-	return &AuthService{BaseURL: baseURL}
+func NewAuthService(base *Endpoint, client *httpclient.Client, identity *IdentityService) *AuthService {
+	return &AuthService{base: base, client: client, identity: identity}
 }
 
 func (a *AuthService) Login(username, password string) error {
@@ -26,20 +31,19 @@ func (a *AuthService) Login(username, password string) error {
 		"password": password,
 	}
 
-	body, _ := json.Marshal(req)
+	httpReq, err := a.client.NewRequest("POST", a.base.URL()+"/auth/login", req)
+	if err != nil {
+		return err
+	}
 
-	resp, err := http.Post(
-		a.BaseURL+"/auth/login",
-		"application/json",
-		bytes.NewBuffer(body),
-	)
+	resp, err := a.client.Do(httpReq)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("invalid username or password")
+		return httpclient.ParseError(resp)
 	}
 
 	var result struct {
@@ -50,6 +54,16 @@ func (a *AuthService) Login(username, password string) error {
 		return err
 	}
 
+	// File the token under the account it belongs to
+	account := username
+	if claims, err := utils.ParseTokenUnverified(result.Token); err == nil && claims.Username != "" {
+		account = claims.Username
+	}
+	if _, err := utils.UseAccount(account); err != nil {
+		return err
+	}
+	a.identity.Invalidate()
+
 	if err := utils.SaveToken(result.Token); err != nil {
 		return err
 	}
@@ -64,64 +78,262 @@ func (a *AuthService) Login(username, password string) error {
 
 	return nil
 }
+
+// ValidateSignup checks the form before it is submitted, for live feedback
+func (a *AuthService) ValidateSignup(username, password string) validation.Result {
+	return validation.ValidateSignup(username, password)
+}
+
+// Signup validates the form locally, returning a validation.Error with
+// per-field problems, before creating the account on the server
 func (a *AuthService) Signup(username, password string) error {
+	if err := validation.ValidateSignup(username, password).Err(); err != nil {
+		return err
+	}
+
 	req := map[string]string{
 		"username": username,
 		"password": password,
 	}
 
-	body, _ := json.Marshal(req)
-
-	resp, err := http.Post(
-		a.BaseURL+"/auth/signup",
-		"application/json",
-		bytes.NewBuffer(body),
-	)
+	httpReq, err := a.client.NewRequest("POST", a.base.URL()+"/auth/signup", req)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
+	resp, err := a.client.Do(httpReq)
 	if err != nil {
 		return err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode >= 300 {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf(string(b))
+		return httpclient.ParseError(resp)
 	}
 
 	return nil
 }
 
+// RefreshToken exchanges the current token for a fresh one
+func (a *AuthService) RefreshToken() error {
+	httpReq, err := a.client.NewAuthRequest("POST", a.base.URL()+"/auth/refresh", nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return httpclient.ParseError(resp)
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	if result.Token == "" {
+		return fmt.Errorf("refresh response did not contain a token")
+	}
+
+	return utils.SaveToken(result.Token)
+}
+
+// Logout tears the session down, forgets the token and leaves no account active
 func (a *AuthService) Logout() error {
-	return utils.ClearToken()
+	if a.OnLogout != nil {
+		a.OnLogout()
+	}
+	a.identity.Invalidate()
+
+	account := utils.ActiveAccount()
+	if err := utils.ClearToken(); err != nil {
+		return err
+	}
+	if account != "" {
+		// Queued library changes are kept for the next login to send
+		if err := utils.ClearAccountData(account, libraryQueueFile); err != nil {
+			log.Printf("Failed to clear cached data for %s: %v", account, err)
+		}
+	}
+	return utils.ClearActiveAccount()
+}
+
+// GetIdentity returns the logged-in user; Verified is false when the server
+// could not be asked and the token claims were used instead
+func (a *AuthService) GetIdentity() (*Identity, error) {
+	return a.identity.Display()
 }
 
 func (a *AuthService) GetCurrentUsername() (string, error) {
-	token, err := utils.LoadToken()
+	identity, err := a.identity.Display()
 	if err != nil {
 		return "", err
 	}
 
-	claims, err := utils.ValidateToken(token)
+	return identity.Username, nil
+}
+
+// IsAdmin only decides what the UI shows; the server enforces admin rights
+func (a *AuthService) IsAdmin() (bool, error) {
+	identity, err := a.identity.Display()
 	if err != nil {
-		return "", err
+		return false, err
 	}
 
-	return claims.Username, nil
+	return identity.Role == "admin", nil
 }
 
-func (a *AuthService) IsAdmin() (bool, error) {
-	token, err := utils.LoadToken()
+// DeletionRequest is the server's answer to RequestAccountDeletion
+type DeletionRequest struct {
+	ConfirmationToken string    `json:"confirmation_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// ChangePassword sets a new password after confirming the current one
+func (a *AuthService) ChangePassword(currentPassword, newPassword string) error {
+	if currentPassword == "" || newPassword == "" {
+		return fmt.Errorf("current and new password required")
+	}
+	if problems := validation.ValidatePassword(newPassword, utils.ActiveAccount()); len(problems) > 0 {
+		return &validation.Error{Code: "validation_failed", Message: problems[0].Message, Problems: problems}
+	}
+
+	var result struct {
+		Token string `json:"token"`
+	}
+	err := a.accountRequest("POST", "/auth/password", map[string]string{
+		"current_password": currentPassword,
+		"new_password":     newPassword,
+	}, &result)
 	if err != nil {
-		return false, err
+		return err
+	}
+
+	return a.afterCredentialChange(result.Token)
+}
+
+// ChangeUsername renames the account; the password confirms the change
+func (a *AuthService) ChangeUsername(newUsername, password string) error {
+	if newUsername == "" || password == "" {
+		return fmt.Errorf("new username and password required")
+	}
+	if problems := validation.ValidateUsername(newUsername); len(problems) > 0 {
+		return &validation.Error{Code: "validation_failed", Message: problems[0].Message, Problems: problems}
 	}
 
-	claims, err := utils.ValidateToken(token)
+	var result struct {
+		Token string `json:"token"`
+	}
+	err := a.accountRequest("PATCH", "/auth/username", map[string]string{
+		"new_username": newUsername,
+		"password":     password,
+	}, &result)
 	if err != nil {
-		return false, err
+		return err
+	}
+
+	// Keep the token, caches and device ID with the renamed account
+	if old := utils.ActiveAccount(); old != "" {
+		if err := utils.RenameAccount(old, newUsername); err != nil {
+			log.Printf("Failed to rename local account %s: %v", old, err)
+		}
+	}
+
+	return a.afterCredentialChange(result.Token)
+}
+
+// RequestAccountDeletion asks the server for a short-lived token that
+// DeleteAccount must present, so deletion always takes two deliberate steps
+func (a *AuthService) RequestAccountDeletion(password string) (*DeletionRequest, error) {
+	if password == "" {
+		return nil, fmt.Errorf("password required")
+	}
+
+	var result DeletionRequest
+	err := a.accountRequest("POST", "/auth/account/delete-request", map[string]string{
+		"password": password,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteAccount permanently deletes the account, then logs out and forgets
+// everything stored locally for it
+func (a *AuthService) DeleteAccount(confirmationToken string) error {
+	if confirmationToken == "" {
+		return fmt.Errorf("confirmation token required")
+	}
+
+	err := a.accountRequest("DELETE", "/auth/account", map[string]string{
+		"confirmation_token": confirmationToken,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	username := utils.ActiveAccount()
+	if err := a.Logout(); err != nil {
+		log.Printf("Failed to log out deleted account: %v", err)
 	}
+	if username != "" {
+		return utils.RemoveAccount(username)
+	}
+	return nil
+}
+
+// accountRequest sends an account change; result may be nil. A 401 here is
+// a wrong password rather than an expired session.
+func (a *AuthService) accountRequest(method, path string, body interface{}, result interface{}) error {
+	httpReq, err := a.client.NewAuthRequest(method, a.base.URL()+path, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.client.Do(httpclient.WithCredentialCheck(httpReq))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-	return claims.Role == "admin", nil
+	if resp.StatusCode >= 300 {
+		return httpclient.ParseError(resp)
+	}
+
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// afterCredentialChange drops what was tied to the old credentials. With a
+// fresh token the services reconnect under it; without one the server has
+// revoked the session and the user must log in again.
+func (a *AuthService) afterCredentialChange(token string) error {
+	a.identity.Invalidate()
+
+	if token == "" {
+		return a.Logout()
+	}
+
+	if err := utils.SaveToken(token); err != nil {
+		return err
+	}
+	if a.OnLoginSuccess != nil {
+		if err := a.OnLoginSuccess(); err != nil {
+			log.Printf("Failed to reinitialize services: %v", err)
+		}
+	}
+	return nil
 }
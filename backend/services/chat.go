@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"mangahub-desktop/backend/transport"
 	"mangahub-desktop/backend/utils"
 	"net/http"
 	"sync"
@@ -39,11 +40,13 @@ type ChatService struct {
 	mu           sync.Mutex
 	cancelRead   context.CancelFunc
 	isConnecting bool
+	conns        *ConnectionManager
 }
 
-func NewChatService(wsBaseURL string) *ChatService {
+func NewChatService(wsBaseURL string, conns *ConnectionManager) *ChatService {
 	return &ChatService{
 		wsBaseURL: wsBaseURL,
+		conns:     conns,
 	}
 }
 
@@ -106,6 +109,7 @@ func (c *ChatService) Connect(wsBaseURL string, room string) error {
 
 	url := fmt.Sprintf("%s/ws/chat?room=%s", wsBaseURL, room)
 	utils.LogInfo(fmt.Sprintf("🔌 Connecting to chat room at %s", url))
+	c.conns.connecting(TransportWebSocket, wsBaseURL)
 
 	jwt, err := utils.LoadToken()
 	if err != nil {
@@ -117,11 +121,12 @@ func (c *ChatService) Connect(wsBaseURL string, room string) error {
 	header := http.Header{}
 	header.Set("Authorization", "Bearer "+jwt)
 
-	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	conn, _, err := transport.DialWebSocket(url, header)
 	if err != nil {
 		c.mu.Lock()
 		c.isConnecting = false
 		c.mu.Unlock()
+		c.conns.failed(TransportWebSocket, wsBaseURL, err)
 		return err
 	}
 
@@ -137,6 +142,7 @@ func (c *ChatService) Connect(wsBaseURL string, room string) error {
 	c.mu.Unlock()
 
 	utils.LogInfo("✅ Connection established, starting read loop...")
+	c.conns.connected(TransportWebSocket, wsBaseURL)
 	go c.readLoop(readCtx)
 
 	// Emit connected event on success
@@ -176,6 +182,10 @@ func (c *ChatService) readLoop(readCtx context.Context) {
 		_, msg, err := conn.ReadMessage()
 		if err != nil {
 			utils.LogError(fmt.Sprintf("❌ Read error: %v", err))
+			// Closed on purpose by Connect/Disconnect is not a transport failure
+			if readCtx.Err() == nil {
+				c.conns.failed(TransportWebSocket, "", err)
+			}
 			if ctx != nil {
 				runtime.EventsEmit(ctx, "chat:disconnected")
 			}
@@ -267,5 +277,6 @@ func (c *ChatService) Disconnect() {
 	}
 
 	c.isConnecting = false
+	c.conns.disconnected(TransportWebSocket)
 	utils.LogInfo("✅ Chat service disconnected")
 }
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Transports tracked by the ConnectionManager
+const (
+	TransportREST      = "rest"
+	TransportWebSocket = "websocket"
+	TransportSync      = "sync"
+	TransportNotify    = "notify"
+	TransportGRPC      = "grpc"
+)
+
+// Connection states
+const (
+	StateDisconnected = "disconnected"
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateError        = "error"
+)
+
+var transports = []string{
+	TransportREST,
+	TransportWebSocket,
+	TransportSync,
+	TransportNotify,
+	TransportGRPC,
+}
+
+type TransportStatus struct {
+	Transport   string    `json:"transport"`
+	State       string    `json:"state"`
+	Endpoint    string    `json:"endpoint,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastErrorAt time.Time `json:"last_error_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ConnectionManager owns the state of every transport and emits a
+// connection:status event on each transition
+type ConnectionManager struct {
+	ctx       context.Context
+	mu        sync.Mutex
+	statuses  map[string]*TransportStatus
+	listeners []func(TransportStatus)
+}
+
+func NewConnectionManager() *ConnectionManager {
+	statuses := make(map[string]*TransportStatus, len(transports))
+	for _, t := range transports {
+		statuses[t] = &TransportStatus{Transport: t, State: StateDisconnected}
+	}
+	return &ConnectionManager{statuses: statuses}
+}
+
+func (c *ConnectionManager) SetContext(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx = ctx
+}
+
+// GetStatus returns the current state of all transports
+func (c *ConnectionManager) GetStatus() []TransportStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make([]TransportStatus, 0, len(transports))
+	for _, t := range transports {
+		result = append(result, *c.statuses[t])
+	}
+	return result
+}
+
+// Connected reports whether transport last succeeded
+func (c *ConnectionManager) Connected(transport string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status, ok := c.statuses[transport]
+	return ok && status.State == StateConnected
+}
+
+// Reset marks every transport disconnected, e.g. after logout
+func (c *ConnectionManager) Reset() {
+	for _, t := range transports {
+		c.disconnected(t)
+	}
+}
+
+// onTransition registers fn to be called after every emitted transition
+func (c *ConnectionManager) onTransition(fn func(TransportStatus)) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, fn)
+}
+
+func (c *ConnectionManager) connecting(transport, endpoint string) {
+	c.set(transport, StateConnecting, endpoint, nil)
+}
+
+func (c *ConnectionManager) connected(transport, endpoint string) {
+	c.set(transport, StateConnected, endpoint, nil)
+}
+
+func (c *ConnectionManager) disconnected(transport string) {
+	c.set(transport, StateDisconnected, "", nil)
+}
+
+func (c *ConnectionManager) failed(transport, endpoint string, err error) {
+	c.set(transport, StateError, endpoint, err)
+}
+
+// set records a transition; an empty endpoint keeps the previous one.
+// Nothing is emitted when neither state, endpoint nor error changed.
+func (c *ConnectionManager) set(transport, state, endpoint string, err error) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	status, ok := c.statuses[transport]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+
+	changed := status.State != state
+	if endpoint != "" && endpoint != status.Endpoint {
+		status.Endpoint = endpoint
+		changed = true
+	}
+	if err != nil {
+		status.LastError = err.Error()
+		status.LastErrorAt = time.Now()
+		changed = true
+	}
+	if !changed {
+		c.mu.Unlock()
+		return
+	}
+
+	status.State = state
+	status.UpdatedAt = time.Now()
+	snapshot := *status
+	ctx := c.ctx
+	listeners := append([]func(TransportStatus){}, c.listeners...)
+	c.mu.Unlock()
+
+	if err != nil {
+		utils.LogError(fmt.Sprintf("🔌 %s %s: %v", transport, state, err))
+	} else {
+		utils.LogInfo(fmt.Sprintf("🔌 %s %s %s", transport, state, snapshot.Endpoint))
+	}
+
+	if ctx != nil {
+		runtime.EventsEmit(ctx, "connection:status", snapshot)
+	}
+	for _, fn := range listeners {
+		fn(snapshot)
+	}
+}
+
+// restTracker records REST reachability for every request passing through it
+type restTracker struct {
+	next http.RoundTripper
+	conn *ConnectionManager
+}
+
+// NewRESTTracker wraps next so REST calls update the rest transport state
+func NewRESTTracker(conn *ConnectionManager, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &restTracker{next: next, conn: conn}
+}
+
+func (t *restTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint := req.URL.Scheme + "://" + req.URL.Host
+
+	resp, err := t.next.RoundTrip(req)
+	switch {
+	case err != nil:
+		t.conn.failed(TransportREST, endpoint, err)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		t.conn.failed(TransportREST, endpoint, fmt.Errorf("server returned %s", resp.Status))
+	default:
+		t.conn.connected(TransportREST, endpoint)
+	}
+	return resp, err
+}
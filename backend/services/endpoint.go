@@ -0,0 +1,28 @@
+package services
+
+import "sync"
+
+// Endpoint is the REST base URL shared by the HTTP services. Settings rebinds
+// it in one step while background refreshes and replays read it.
+type Endpoint struct {
+	url string
+	mu  sync.Mutex
+}
+
+func NewEndpoint(url string) *Endpoint {
+	return &Endpoint{url: url}
+}
+
+// URL returns the base URL currently bound
+func (e *Endpoint) URL() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.url
+}
+
+// Set rebinds every service sharing the endpoint to url
+func (e *Endpoint) Set(url string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.url = url
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/mal"
+	"mangahub-desktop/backend/models"
+	"mangahub-desktop/backend/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Export formats
+const (
+	ExportJSON = "json"
+	ExportCSV  = "csv"
+	ExportMAL  = "mal"
+)
+
+const (
+	// backupFormat identifies a MangaHub JSON backup
+	backupFormat  = "mangahub-library"
+	backupVersion = 1
+	// exportDetailWorkers bounds concurrent manga detail lookups
+	exportDetailWorkers = 4
+)
+
+// LibraryBackup is the JSON export: the library, the catalog details of
+// every manga in it and the reading history, as the server returned them
+type LibraryBackup struct {
+	Format     string                  `json:"format"`
+	Version    int                     `json:"version"`
+	ExportedAt time.Time               `json:"exported_at"`
+	Account    string                  `json:"account,omitempty"`
+	Library    models.ReadingLists     `json:"library"`
+	Manga      map[string]models.Manga `json:"manga"`
+	History    []ProgressHistoryItem   `json:"history"`
+}
+
+// ExportService writes the library out for backup or for other trackers
+type ExportService struct {
+	ctx     context.Context
+	library *LibraryService
+	manga   *MangaService
+}
+
+func NewExportService(library *LibraryService, manga *MangaService) *ExportService {
+	return &ExportService{library: library, manga: manga}
+}
+
+func (s *ExportService) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// ExportLibrary asks where to save and writes the library in format (json,
+// csv or mal). It returns the path written, or "" when the dialog was
+// cancelled.
+func (s *ExportService) ExportLibrary(format string) (string, error) {
+	ext, filter, err := exportFileType(format)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := runtime.SaveFileDialog(s.ctx, runtime.SaveDialogOptions{
+		Title:           "Export library",
+		DefaultFilename: fmt.Sprintf("mangahub-library-%s.%s", time.Now().Format("2006-01-02"), ext),
+		Filters:         []runtime.FileFilter{filter},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if err := s.ExportLibraryTo(format, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// ExportLibraryTo writes the library in format to path
+func (s *ExportService) ExportLibraryTo(format, path string) error {
+	if _, _, err := exportFileType(format); err != nil {
+		return err
+	}
+	backup, err := s.collect()
+	if err != nil {
+		return err
+	}
+
+	// Write next to the target first so a failed export leaves no half file
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".mangahub-export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	switch format {
+	case ExportJSON:
+		err = writeJSONBackup(w, backup)
+	case ExportCSV:
+		err = writeCSV(w, backup)
+	case ExportMAL:
+		err = writeMAL(w, backup)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		// CreateTemp makes the file private; exports are ordinary documents
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	utils.LogInfo(fmt.Sprintf("📤 Exported %d library entries as %s to %s", len(entries(backup.Library)), format, path))
+	return nil
+}
+
+func exportFileType(format string) (string, runtime.FileFilter, error) {
+	switch format {
+	case ExportJSON:
+		return "json", runtime.FileFilter{DisplayName: "MangaHub backup (*.json)", Pattern: "*.json"}, nil
+	case ExportCSV:
+		return "csv", runtime.FileFilter{DisplayName: "Spreadsheet (*.csv)", Pattern: "*.csv"}, nil
+	case ExportMAL:
+		return "xml", runtime.FileFilter{DisplayName: "MyAnimeList XML (*.xml)", Pattern: "*.xml"}, nil
+	}
+	return "", runtime.FileFilter{}, fmt.Errorf("unknown export format %q", format)
+}
+
+// collect gathers the library, manga details and history. Details or
+// history the server cannot give right now are left out rather than
+// failing the export, so the stored library can still be saved offline.
+func (s *ExportService) collect() (*LibraryBackup, error) {
+	lists, err := s.library.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &LibraryBackup{
+		Format:     backupFormat,
+		Version:    backupVersion,
+		ExportedAt: time.Now().UTC(),
+		Account:    utils.ActiveAccount(),
+		Library:    *lists,
+		Manga:      s.details(entries(*lists)),
+		History:    []ProgressHistoryItem{},
+	}
+
+	if history, err := s.library.GetProgressHistory(""); err == nil {
+		backup.History = history.History
+	} else {
+		utils.LogError(fmt.Sprintf("📤 Exporting without reading history: %v", err))
+	}
+	return backup, nil
+}
+
+// details looks up the catalog entry of every manga in the library
+func (s *ExportService) details(list []models.ReadingEntry) map[string]models.Manga {
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		manga   = make(map[string]models.Manga, len(list))
+		missing int
+		ids     = make(chan string)
+	)
+
+	for w := 0; w < exportDetailWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				m, err := s.manga.ListMangaDetail(id)
+				mu.Lock()
+				if err == nil {
+					manga[id] = *m
+				} else {
+					missing++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, e := range list {
+		ids <- e.MangaID
+	}
+	close(ids)
+	wg.Wait()
+
+	if missing > 0 {
+		utils.LogError(fmt.Sprintf("📤 No catalog details for %d manga, exporting their IDs as titles", missing))
+	}
+	return manga
+}
+
+// entries flattens the lists in the order the Library page shows them
+func entries(lists models.ReadingLists) []models.ReadingEntry {
+	all := make([]models.ReadingEntry, 0, len(lists.Reading)+len(lists.Completed)+len(lists.PlanToRead))
+	all = append(all, lists.Reading...)
+	all = append(all, lists.Completed...)
+	all = append(all, lists.PlanToRead...)
+	return all
+}
+
+// titleOf falls back to the manga ID when the catalog was unreachable
+func titleOf(backup *LibraryBackup, mangaID string) string {
+	if m, ok := backup.Manga[mangaID]; ok && m.Title != "" {
+		return m.Title
+	}
+	return mangaID
+}
+
+func writeJSONBackup(w io.Writer, backup *LibraryBackup) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(backup)
+}
+
+// writeCSV writes one row per entry. A byte order mark lets spreadsheet
+// apps detect UTF-8, so Japanese titles survive.
+func writeCSV(w io.Writer, backup *LibraryBackup) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Title", "Author", "Status", "Chapter", "Volume", "Notes", "Last Updated"})
+	for _, e := range entries(backup.Library) {
+		volume, notes, updated := "", "", ""
+		if e.Volume != nil {
+			volume = strconv.Itoa(*e.Volume)
+		}
+		if e.Notes != nil {
+			notes = *e.Notes
+		}
+		if !e.LastUpdated.IsZero() {
+			updated = e.LastUpdated.Local().Format("2006-01-02 15:04")
+		}
+		cw.Write([]string{
+			titleOf(backup, e.MangaID),
+			backup.Manga[e.MangaID].Author,
+			e.Status,
+			strconv.Itoa(e.CurrentChapter),
+			volume,
+			notes,
+			updated,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeMAL writes a MyAnimeList list export. Start and finish dates come
+// from the reading history: the first chapter read, and the last one for
+// completed series.
+func writeMAL(w io.Writer, backup *LibraryBackup) error {
+	read := map[string][]string{}
+	for _, h := range backup.History {
+		if len(h.Date) >= len("2006-01-02") {
+			if day, err := time.Parse("2006-01-02", h.Date[:10]); err == nil {
+				read[h.MangaID] = append(read[h.MangaID], day.Format("2006-01-02"))
+			}
+		}
+	}
+
+	export := &mal.Export{Info: mal.Info{UserName: backup.Account}}
+	for _, e := range entries(backup.Library) {
+		entry := mal.Entry{
+			Title:        titleOf(backup, e.MangaID),
+			Chapters:     backup.Manga[e.MangaID].ChapterCount,
+			ReadChapters: e.CurrentChapter,
+			Status:       mal.MALStatus(e.Status),
+		}
+		if e.Volume != nil {
+			entry.ReadVolumes = *e.Volume
+		}
+		if e.Notes != nil {
+			entry.Comments = *e.Notes
+		}
+		if days := read[e.MangaID]; len(days) > 0 {
+			sort.Strings(days)
+			entry.StartDate = days[0]
+			if e.Status == "completed" {
+				entry.FinishDate = days[len(days)-1]
+			}
+		}
+		export.Manga = append(export.Manga, entry)
+	}
+	return mal.Write(w, export)
+}
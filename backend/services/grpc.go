@@ -1,23 +1,56 @@
 package services
 
 import (
+	"fmt"
+
 	grpcclient "mangahub-desktop/backend/grpc-client"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-type GRPCService struct{}
+type GRPCService struct {
+	conns    *ConnectionManager
+	identity *IdentityService
+}
+
+func NewGRPCService(conns *ConnectionManager, identity *IdentityService) *GRPCService {
+	return &GRPCService{conns: conns, identity: identity}
+}
 
-func NewGRPCService() *GRPCService {
-	return &GRPCService{}
+// track records gRPC reachability. Application errors (not found, invalid
+// argument...) still prove the server answered.
+func (g *GRPCService) track(err error) {
+	addr := grpcclient.Address()
+	if err == nil {
+		g.conns.connected(TransportGRPC, addr)
+		return
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Unknown:
+		g.conns.failed(TransportGRPC, addr, err)
+	default:
+		g.conns.connected(TransportGRPC, addr)
+	}
 }
 
 // GetMangaByID fetches manga details via gRPC
 func (g *GRPCService) GetMangaByID(mangaID string) (map[string]string, error) {
-	return grpcclient.GetMangaByID(mangaID)
+	manga, err := grpcclient.GetMangaByID(mangaID)
+	g.track(err)
+	return manga, err
 }
 
 // UpdateProgress updates reading progress via gRPC
 func (g *GRPCService) UpdateProgress(mangaID string, chapter int64) error {
-	return grpcclient.UpdateProgress(mangaID, chapter)
+	identity, err := g.identity.Current()
+	if err != nil {
+		return fmt.Errorf("could not confirm the logged-in user: %w", err)
+	}
+
+	err = grpcclient.UpdateProgress(identity.UserID, mangaID, chapter)
+	g.track(err)
+	return err
 }
 
 // StartGRPCClient starts the gRPC client connection
@@ -34,6 +67,7 @@ type SearchResult struct {
 // SearchManga searches for manga by keyword via gRPC
 func (g *GRPCService) SearchManga(keyword string, page int32, pageSize int32) (*SearchResult, error) {
 	results, total, err := grpcclient.SearchManga(keyword, page, pageSize)
+	g.track(err)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/httpclient"
+	"mangahub-desktop/backend/utils"
+)
+
+// identityTTL is how long a profile fetched from /auth/me is reused
+const identityTTL = 10 * time.Minute
+
+// Identity is the logged-in user
+type Identity struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	// Verified is false when the identity was only decoded from the token
+	Verified bool `json:"verified"`
+}
+
+// IdentityService resolves who is logged in by asking the server. The client
+// never holds the JWT secret, so token claims are only trusted for display.
+type IdentityService struct {
+	base      *Endpoint
+	client    *httpclient.Client
+	mu        sync.Mutex
+	cached    *Identity
+	token     string // token the cached identity belongs to
+	fetchedAt time.Time
+}
+
+func NewIdentityService(base *Endpoint, client *httpclient.Client) *IdentityService {
+	return &IdentityService{base: base, client: client}
+}
+
+// Current returns the server-confirmed identity, cached per token
+func (i *IdentityService) Current() (*Identity, error) {
+	token, _ := utils.LoadToken()
+	if token == "" {
+		return nil, fmt.Errorf("not logged in")
+	}
+
+	i.mu.Lock()
+	if i.cached != nil && i.token == token && time.Since(i.fetchedAt) < identityTTL {
+		identity := *i.cached
+		i.mu.Unlock()
+		return &identity, nil
+	}
+	i.mu.Unlock()
+
+	identity, err := i.fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	i.mu.Lock()
+	i.cached = identity
+	i.token = token
+	i.fetchedAt = time.Now()
+	i.mu.Unlock()
+
+	if active := utils.ActiveAccount(); active == identity.Username {
+		utils.UpdateAccount(active, identity.UserID, identity.Role)
+	}
+
+	result := *identity
+	return &result, nil
+}
+
+// Display returns the identity for showing in the UI: the server's answer
+// when available, otherwise the unverified token claims
+func (i *IdentityService) Display() (*Identity, error) {
+	identity, err := i.Current()
+	if err == nil {
+		return identity, nil
+	}
+	if httpclient.IsUnauthorized(err) {
+		return nil, err
+	}
+
+	token, _ := utils.LoadToken()
+	if token == "" {
+		return nil, fmt.Errorf("not logged in")
+	}
+	claims, claimsErr := utils.ParseTokenUnverified(token)
+	if claimsErr != nil {
+		return nil, err
+	}
+	return &Identity{
+		UserID:   claims.UserId,
+		Username: claims.Username,
+		Role:     claims.Role,
+	}, nil
+}
+
+// Invalidate drops the cached identity, e.g. after the account changed
+func (i *IdentityService) Invalidate() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.cached = nil
+	i.token = ""
+}
+
+func (i *IdentityService) fetch() (*Identity, error) {
+	httpReq, err := i.client.NewAuthRequest("GET", i.base.URL()+"/auth/me", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := i.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, httpclient.ParseError(resp)
+	}
+
+	// Older servers name the id field "id"
+	var result struct {
+		UserID   int64  `json:"user_id"`
+		ID       int64  `json:"id"`
+		Username string `json:"username"`
+		Role     string `json:"role"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{
+		UserID:   result.UserID,
+		Username: result.Username,
+		Role:     result.Role,
+		Verified: true,
+	}
+	if identity.UserID == 0 {
+		identity.UserID = result.ID
+	}
+	return identity, nil
+}
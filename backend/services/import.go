@@ -0,0 +1,525 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/httpclient"
+	"mangahub-desktop/backend/mal"
+	"mangahub-desktop/backend/match"
+	"mangahub-desktop/backend/models"
+	"mangahub-desktop/backend/tachiyomi"
+	"mangahub-desktop/backend/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Import sources
+const (
+	ImportSourceMAL       = "mal"
+	ImportSourceTachiyomi = "tachiyomi"
+)
+
+// States of an item in an import preview
+const (
+	ImportMatched   = "matched"
+	ImportAmbiguous = "ambiguous"
+	ImportUnmatched = "unmatched"
+	ImportSkipped   = "skipped"
+)
+
+// Outcomes of applying an item
+const (
+	ImportResultImported = "imported"
+	ImportResultQueued   = "queued"
+	ImportResultSkipped  = "skipped"
+	ImportResultFailed   = "failed"
+)
+
+const (
+	// maxImportCandidates is how many catalog titles are offered per item
+	maxImportCandidates = 5
+	// importSearchWorkers bounds concurrent catalog searches while matching
+	importSearchWorkers = 4
+)
+
+// ImportCandidate is a catalog manga that may be the imported series
+type ImportCandidate struct {
+	Manga models.Manga `json:"manga"`
+	Score float64      `json:"score"`
+}
+
+// ImportItem is one series from an import file and what it matched
+type ImportItem struct {
+	Index        int    `json:"index"`
+	Title        string `json:"title"`
+	Author       string `json:"author,omitempty"`
+	SourceStatus string `json:"source_status"`
+	// Status is the MangaHub list it goes to, "" when it has none
+	Status  string `json:"status"`
+	Chapter int    `json:"chapter"`
+	Volume  int    `json:"volume,omitempty"`
+	Notes   string `json:"notes,omitempty"`
+	State   string `json:"state"`
+	Reason  string `json:"reason,omitempty"`
+	// MangaID is the match that will be imported unless a decision says otherwise
+	MangaID    string            `json:"manga_id,omitempty"`
+	Candidates []ImportCandidate `json:"candidates"`
+}
+
+// ImportPreview is shown for review before anything is written
+type ImportPreview struct {
+	ID        string       `json:"id"`
+	Source    string       `json:"source"`
+	Items     []ImportItem `json:"items"`
+	Matched   int          `json:"matched"`
+	Ambiguous int          `json:"ambiguous"`
+	Unmatched int          `json:"unmatched"`
+	Skipped   int          `json:"skipped"`
+}
+
+// ImportDecision overrides the preview for one item
+type ImportDecision struct {
+	Index int `json:"index"`
+	// MangaID is the chosen match; "" leaves the item out
+	MangaID string `json:"manga_id"`
+	// Status replaces the mapped list when set
+	Status string `json:"status,omitempty"`
+}
+
+// ImportResult is what happened to one item
+type ImportResult struct {
+	Index   int    `json:"index"`
+	Title   string `json:"title"`
+	MangaID string `json:"manga_id,omitempty"`
+	Result  string `json:"result"`
+	// Reason says why an item was skipped
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportReport summarises an applied import
+type ImportReport struct {
+	Source   string         `json:"source"`
+	Imported int            `json:"imported"`
+	Queued   int            `json:"queued"`
+	Skipped  int            `json:"skipped"`
+	Failed   int            `json:"failed"`
+	Results  []ImportResult `json:"results"`
+}
+
+// ImportProgress is emitted as import:progress while matching and applying
+type ImportProgress struct {
+	Stage string `json:"stage"` // matching | applying
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// ImportService brings reading lists from other trackers into the library.
+// Files are matched against the catalog into a preview first, and only
+// written through LibraryService once the user reviewed it.
+type ImportService struct {
+	ctx      context.Context
+	library  *LibraryService
+	manga    *MangaService
+	mu       sync.Mutex
+	previews map[string]*ImportPreview
+}
+
+func NewImportService(library *LibraryService, manga *MangaService) *ImportService {
+	return &ImportService{
+		library:  library,
+		manga:    manga,
+		previews: map[string]*ImportPreview{},
+	}
+}
+
+func (s *ImportService) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// ChooseMALExport asks for a MyAnimeList export and previews it. It returns
+// nil when the dialog was cancelled.
+func (s *ImportService) ChooseMALExport() (*ImportPreview, error) {
+	path, err := runtime.OpenFileDialog(s.ctx, runtime.OpenDialogOptions{
+		Title: "Import MyAnimeList export",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "MyAnimeList export (*.xml, *.xml.gz)", Pattern: "*.xml;*.gz"},
+		},
+	})
+	if err != nil || path == "" {
+		return nil, err
+	}
+	return s.PreviewMALImport(path)
+}
+
+// PreviewMALImport parses a MyAnimeList manga list export and matches every
+// title against the catalog
+func (s *ImportService) PreviewMALImport(path string) (*ImportPreview, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	export, err := mal.Parse(file)
+	if err != nil {
+		return nil, err
+	}
+	utils.LogInfo(fmt.Sprintf("📥 Importing %d titles from MyAnimeList export of %q", len(export.Manga), export.Info.UserName))
+
+	items := make([]ImportItem, 0, len(export.Manga))
+	for i, entry := range export.Manga {
+		item := ImportItem{
+			Index:        i,
+			Title:        entry.Title,
+			SourceStatus: entry.Status,
+			Chapter:      entry.ReadChapters,
+			Volume:       entry.ReadVolumes,
+			Notes:        entry.Comments,
+		}
+		if status, ok := mal.LibraryStatus(entry.Status); ok {
+			item.Status = status
+		} else {
+			item.State = ImportSkipped
+			item.Reason = fmt.Sprintf("%q has no MangaHub list", entry.Status)
+		}
+		items = append(items, item)
+	}
+
+	return s.preview(ImportSourceMAL, items)
+}
+
+// ChooseTachiyomiBackup asks for a Tachiyomi or Mihon backup and previews
+// it. It returns nil when the dialog was cancelled.
+func (s *ImportService) ChooseTachiyomiBackup() (*ImportPreview, error) {
+	path, err := runtime.OpenFileDialog(s.ctx, runtime.OpenDialogOptions{
+		Title: "Import Tachiyomi/Mihon backup",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "Tachiyomi/Mihon backup (*.tachibk, *.proto.gz)", Pattern: "*.tachibk;*.gz"},
+		},
+	})
+	if err != nil || path == "" {
+		return nil, err
+	}
+	return s.PreviewTachiyomiImport(path)
+}
+
+// PreviewTachiyomiImport decodes a Tachiyomi or Mihon backup and matches
+// every series in its library against the catalog. The last chapter marked
+// read becomes the progress to apply.
+func (s *ImportService) PreviewTachiyomiImport(path string) (*ImportPreview, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	backup, err := tachiyomi.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	utils.LogInfo(fmt.Sprintf("📥 Importing %d series from Tachiyomi backup", len(backup.Manga)))
+
+	items := make([]ImportItem, 0, len(backup.Manga))
+	for i, manga := range backup.Manga {
+		// Progress is tracked in whole chapters; 12.5 read means 12 finished
+		chapter := int(manga.LastRead())
+		item := ImportItem{
+			Index:        i,
+			Title:        manga.Title,
+			Author:       manga.Author,
+			SourceStatus: "in library",
+			Chapter:      chapter,
+			Status:       "reading",
+		}
+		switch {
+		case !manga.Favorite:
+			item.SourceStatus = "history only"
+			item.Status = ""
+			item.State = ImportSkipped
+			item.Reason = "not in the Tachiyomi library"
+		case chapter == 0:
+			item.Status = "plan_to_read"
+		}
+		items = append(items, item)
+	}
+
+	return s.preview(ImportSourceTachiyomi, items)
+}
+
+// preview matches items against the catalog and keeps the result until it
+// is applied or discarded
+func (s *ImportService) preview(source string, items []ImportItem) (*ImportPreview, error) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		done     int
+		netErr   error
+		indexes  = make(chan int)
+		progress = func() {
+			mu.Lock()
+			done++
+			s.emitProgress(ImportProgress{Stage: "matching", Done: done, Total: len(items)})
+			mu.Unlock()
+		}
+	)
+
+	for w := 0; w < importSearchWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := s.match(&items[i]); err != nil && httpclient.IsNetworkError(err) {
+					mu.Lock()
+					netErr = err
+					mu.Unlock()
+				}
+				progress()
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	if netErr != nil {
+		return nil, fmt.Errorf("the catalog is unreachable, try the import again once online: %w", netErr)
+	}
+
+	p := &ImportPreview{
+		ID:     fmt.Sprintf("%s-%d", source, time.Now().UnixNano()),
+		Source: source,
+		Items:  items,
+	}
+	for _, item := range items {
+		switch item.State {
+		case ImportMatched:
+			p.Matched++
+		case ImportAmbiguous:
+			p.Ambiguous++
+		case ImportUnmatched:
+			p.Unmatched++
+		case ImportSkipped:
+			p.Skipped++
+		}
+	}
+
+	s.mu.Lock()
+	s.previews[p.ID] = p
+	s.mu.Unlock()
+
+	utils.LogInfo(fmt.Sprintf("📥 Import preview: %d matched, %d ambiguous, %d unmatched, %d skipped",
+		p.Matched, p.Ambiguous, p.Unmatched, p.Skipped))
+	return p, nil
+}
+
+// match searches the catalog for item's title and fills in its candidates.
+// Skipped items are matched too, so the user can still choose to import them.
+func (s *ImportService) match(item *ImportItem) error {
+	item.Candidates = []ImportCandidate{}
+	setState := func(state, reason string) {
+		if item.State != ImportSkipped {
+			item.State = state
+			item.Reason = reason
+		}
+	}
+
+	results, err := s.manga.SearchMangas(item.Title)
+	if err == nil && len(results) == 0 {
+		if query := match.SearchQuery(item.Title); query != "" {
+			results, err = s.manga.SearchMangas(query)
+		}
+	}
+	if err != nil {
+		setState(ImportUnmatched, "search failed: "+err.Error())
+		return err
+	}
+
+	candidates := make([]match.Candidate, len(results))
+	for i, m := range results {
+		candidates[i] = match.Candidate{Title: m.Title, Author: m.Author}
+	}
+	ranked := match.Rank(item.Title, item.Author, candidates)
+	for _, r := range ranked {
+		if r.Score < match.Plausible || len(item.Candidates) == maxImportCandidates {
+			break
+		}
+		item.Candidates = append(item.Candidates, ImportCandidate{Manga: results[r.Index], Score: r.Score})
+	}
+
+	switch {
+	case len(item.Candidates) == 0:
+		setState(ImportUnmatched, "no similar title in the catalog")
+	case match.Decisive(ranked):
+		item.MangaID = item.Candidates[0].Manga.ID
+		setState(ImportMatched, "")
+	default:
+		setState(ImportAmbiguous, fmt.Sprintf("%d possible matches", len(item.Candidates)))
+	}
+	return nil
+}
+
+// ApplyImport writes a reviewed preview to the library. Items keep their
+// preview match unless a decision overrides it; items without a match or
+// a list are left out. Progress is never moved backwards.
+func (s *ImportService) ApplyImport(previewID string, decisions []ImportDecision) (*ImportReport, error) {
+	s.mu.Lock()
+	p, ok := s.previews[previewID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("import %s not found", previewID)
+	}
+
+	// A bad decision leaves the preview in place, so the review can be fixed
+	items := append([]ImportItem{}, p.Items...)
+	for _, d := range decisions {
+		if d.Index < 0 || d.Index >= len(items) {
+			return nil, fmt.Errorf("no item %d in import", d.Index)
+		}
+		switch d.Status {
+		case "", "reading", "completed", "plan_to_read":
+		default:
+			return nil, fmt.Errorf("unknown list %q for %s", d.Status, items[d.Index].Title)
+		}
+		items[d.Index].MangaID = d.MangaID
+		if d.Status != "" {
+			items[d.Index].Status = d.Status
+		}
+	}
+
+	library, err := s.library.List("")
+	if err != nil {
+		return nil, err
+	}
+
+	// Applying starts here; claiming the preview keeps it from being applied twice
+	s.mu.Lock()
+	_, ok = s.previews[previewID]
+	delete(s.previews, previewID)
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("import %s was already applied", previewID)
+	}
+
+	report := &ImportReport{Source: p.Source, Results: make([]ImportResult, 0, len(items))}
+	for i, item := range items {
+		result := s.applyItem(library, p.Source, item)
+		switch result.Result {
+		case ImportResultImported:
+			report.Imported++
+		case ImportResultQueued:
+			report.Queued++
+		case ImportResultSkipped:
+			report.Skipped++
+		case ImportResultFailed:
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+		s.emitProgress(ImportProgress{Stage: "applying", Done: i + 1, Total: len(items)})
+	}
+
+	utils.LogInfo(fmt.Sprintf("📥 Import applied: %d imported, %d queued, %d skipped, %d failed",
+		report.Imported, report.Queued, report.Skipped, report.Failed))
+	return report, nil
+}
+
+// DiscardImport forgets a preview that will not be applied
+func (s *ImportService) DiscardImport(previewID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.previews, previewID)
+}
+
+// applyItem writes one reviewed item. Tachiyomi only knows what was read, so
+// its lists are used for new entries and existing ones only move forward.
+func (s *ImportService) applyItem(library *models.ReadingLists, source string, item ImportItem) ImportResult {
+	result := ImportResult{Index: item.Index, Title: item.Title, MangaID: item.MangaID, Result: ImportResultSkipped}
+	switch {
+	case item.MangaID == "" && item.State == ImportUnmatched:
+		result.Reason = "no matching manga in the catalog"
+		return result
+	case item.MangaID == "":
+		result.Reason = "no match chosen"
+		return result
+	case item.Status == "":
+		result.Reason = "no list chosen"
+		return result
+	}
+	fail := func(err error) ImportResult {
+		result.Result = ImportResultFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	existing := findEntry(library, item.MangaID)
+	current := 0
+	status := item.Status
+	if existing != nil {
+		current = existing.CurrentChapter
+		if source == ImportSourceTachiyomi && listRank(status) <= listRank(existing.Status) {
+			status = existing.Status
+		}
+	}
+	switch {
+	case existing == nil:
+		if err := s.library.Add(item.MangaID, status, nil); err != nil {
+			return fail(err)
+		}
+	case existing.Status != status:
+		if err := s.library.Update(item.MangaID, status); err != nil {
+			return fail(err)
+		}
+	}
+	// Later items matching the same manga see this one
+	applyMutation(library, Mutation{Kind: MutationUpdate, MangaID: item.MangaID, Status: status, Chapter: &current, QueuedAt: time.Now()})
+	result.Result = ImportResultImported
+
+	if item.Chapter > current {
+		var volume *int
+		if item.Volume > 0 {
+			volume = &item.Volume
+		}
+		var notes *string
+		if item.Notes != "" {
+			notes = &item.Notes
+		}
+		progress, err := s.library.UpdateProgress(item.MangaID, item.Chapter, volume, notes, false)
+		if err != nil {
+			return fail(err)
+		}
+		if progress.Queued {
+			result.Result = ImportResultQueued
+		}
+		applyMutation(library, Mutation{Kind: MutationProgress, MangaID: item.MangaID, Chapter: &item.Chapter, QueuedAt: time.Now()})
+	}
+	// Anything sent while changes are waiting joins the queue behind them
+	if s.library.queue.pending() {
+		result.Result = ImportResultQueued
+	}
+	return result
+}
+
+// listRank orders the lists by how far along a series is
+func listRank(status string) int {
+	switch status {
+	case "plan_to_read":
+		return 1
+	case "reading":
+		return 2
+	case "completed":
+		return 3
+	}
+	return 0
+}
+
+func (s *ImportService) emitProgress(progress ImportProgress) {
+	if s.ctx != nil {
+		runtime.EventsEmit(s.ctx, "import:progress", progress)
+	}
+}
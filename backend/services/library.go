@@ -1,41 +1,75 @@
 package services
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
-	"net/url"
-	"strings"
+	"sync"
 	"time"
 
+	"mangahub-desktop/backend/httpclient"
 	"mangahub-desktop/backend/models"
 	"mangahub-desktop/backend/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// libraryCacheFile holds the active account's library in its account directory
+const libraryCacheFile = "library.json"
+
 type LibraryService struct {
-	BaseURL string
+	base         *Endpoint
+	ctx          context.Context
+	client       *httpclient.Client
+	store        *libraryStore
+	queue        *libraryQueue
+	mu           sync.Mutex
+	refreshing   bool
+	refreshAgain bool
+	offline      bool
+	lastError    string
+	replaying    bool
+	replayAgain  bool
+	retry        *time.Timer
 }
 
-func NewLibraryService(baseURL string) *LibraryService {
-	return &LibraryService{BaseURL: baseURL}
+func NewLibraryService(base *Endpoint, client *httpclient.Client, conns *ConnectionManager) *LibraryService {
+	l := &LibraryService{
+		base:   base,
+		client: client,
+		store:  &libraryStore{},
+		queue:  &libraryQueue{},
+	}
+
+	// The server answering again is the moment to send what was queued
+	conns.onTransition(func(status TransportStatus) {
+		if status.Transport == TransportREST && status.State == StateConnected && l.queue.pending() {
+			go l.ReplayPending()
+		}
+	})
+
+	return l
 }
 
-func authRequest(method, url string, body io.Reader) (*http.Request, error) {
-	jwt, _ := utils.LoadToken()
-	if jwt == "" {
-		return nil, fmt.Errorf("not logged in")
-	}
+func (l *LibraryService) SetContext(ctx context.Context) {
+	l.ctx = ctx
+}
 
-	req, err := http.NewRequest(method, url, body)
-	if err != nil {
-		return nil, err
-	}
+// LibraryState tells the UI how fresh the shown library is
+type LibraryState struct {
+	FetchedAt  time.Time `json:"fetched_at"`
+	Stale      bool      `json:"stale"`
+	Refreshing bool      `json:"refreshing"`
+	// Offline is set when the last refresh could not reach the server
+	Offline   bool   `json:"offline"`
+	LastError string `json:"last_error,omitempty"`
+}
 
-	req.Header.Set("Authorization", "Bearer "+jwt)
-	req.Header.Set("Content-Type", "application/json")
-	return req, nil
+// LibraryUpdate is the library:updated payload
+type LibraryUpdate struct {
+	Lists *models.ReadingLists `json:"lists"`
+	State LibraryState         `json:"state"`
 }
 
 type ProgressUpdateRequest struct {
@@ -55,46 +89,65 @@ type ProgressUpdateResponse struct {
 	TotalChaptersRead int       `json:"total_chapters_read"`
 	ReadingStreak     int       `json:"reading_streak"`
 	NextChapter       int       `json:"next_chapter"`
+	// Queued is set when the update was stored to be sent later
+	Queued bool `json:"queued,omitempty"`
 }
 
+// UpdateProgress records the chapter read. While the server is unreachable
+// the change is queued and the returned response has Queued set.
 func (l *LibraryService) UpdateProgress(mangaID string, chapter int, volume *int, notes *string, force bool) (*ProgressUpdateResponse, error) {
-	// jwt := s.config.GetToken()
-	// if jwt == "" {
-	//     return nil, fmt.Errorf("not authenticated")
-	// }
-	reqBody := ProgressUpdateRequest{
-		MangaID:        mangaID,
-		CurrentChapter: chapter,
-		Volume:         volume,
-		Notes:          notes,
-		Force:          force,
-	}
+	m := Mutation{
+		Kind:    MutationProgress,
+		MangaID: mangaID,
+		Chapter: &chapter,
+		Volume:  volume,
+		Notes:   notes,
+		Force:   force,
+	}
+	result, queued, err := l.send(m)
+	if err != nil || queued == nil {
+		return result, err
+	}
+
+	previous := chapter
+	if queued.BaseChapter != nil {
+		previous = *queued.BaseChapter
+	}
+	return &ProgressUpdateResponse{
+		PreviousChapter: previous,
+		CurrentChapter:  chapter,
+		UpdatedAt:       time.Now(),
+		NextChapter:     chapter + 1,
+		Queued:          true,
+	}, nil
+}
 
-	body, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, err
+func (l *LibraryService) updateProgress(m Mutation) (*ProgressUpdateResponse, error) {
+	reqBody := ProgressUpdateRequest{
+		MangaID:        m.MangaID,
+		CurrentChapter: *m.Chapter,
+		Volume:         m.Volume,
+		Notes:          m.Notes,
+		Force:          m.Force,
 	}
 
-	req, err := authRequest(
+	req, err := l.client.NewAuthRequest(
 		"PATCH",
-		l.BaseURL+"/users/progress",
-		bytes.NewBuffer(body),
+		l.base.URL()+"/users/progress",
+		reqBody,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		errBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("progress update failed: %s", strings.TrimSpace(string(errBody)))
+		return nil, httpclient.ParseError(resp)
 	}
 
 	var result ProgressUpdateResponse
@@ -106,38 +159,162 @@ func (l *LibraryService) UpdateProgress(mangaID string, chapter int, volume *int
 }
 
 // LIST
+
+// List serves the stored library straight away and refreshes it from the
+// server in the background, emitting library:updated when it changes. Only
+// the first call for an account waits for the server.
 func (l *LibraryService) List(status string) (*models.ReadingLists, error) {
-	u, _ := url.Parse(l.BaseURL + "/users/library")
-	if status != "" {
-		q := u.Query()
-		q.Set("status", status)
-		u.RawQuery = q.Encode()
+	stored, err := l.store.load()
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to read stored library: %v", err))
+	}
+	if stored == nil {
+		lists, err := l.fetch()
+		if err != nil {
+			return nil, err
+		}
+		return filterLists(*lists, status), nil
+	}
+
+	l.refreshInBackground()
+	return filterLists(stored.Lists, status), nil
+}
+
+// RefreshLibrary fetches the library from the server and waits for it
+func (l *LibraryService) RefreshLibrary() (*models.ReadingLists, error) {
+	return l.fetch()
+}
+
+// GetCachedLibrary returns the active account's library as last fetched
+func (l *LibraryService) GetCachedLibrary() (*models.ReadingLists, error) {
+	stored, err := l.store.load()
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf("library not fetched yet")
+	}
+	return &stored.Lists, nil
+}
+
+// GetLibraryState reports how fresh the stored library is
+func (l *LibraryService) GetLibraryState() LibraryState {
+	stored, _ := l.store.load()
+	return l.state(stored)
+}
+
+func (l *LibraryService) state(stored *storedLibrary) LibraryState {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	state := LibraryState{
+		Refreshing: l.refreshing,
+		Offline:    l.offline,
+		LastError:  l.lastError,
+		Stale:      true,
+	}
+	if stored != nil {
+		state.FetchedAt = stored.FetchedAt
+		state.Stale = time.Since(stored.FetchedAt) > libraryStaleAfter
+	}
+	return state
+}
+
+// refreshInBackground starts a refresh unless one is running, in which case
+// that one runs again once it finishes
+func (l *LibraryService) refreshInBackground() {
+	l.mu.Lock()
+	if l.refreshing {
+		l.refreshAgain = true
+		l.mu.Unlock()
+		return
+	}
+	l.refreshing = true
+	l.mu.Unlock()
+
+	go func() {
+		for {
+			l.fetch()
+
+			l.mu.Lock()
+			if !l.refreshAgain {
+				l.refreshing = false
+				l.mu.Unlock()
+				return
+			}
+			l.refreshAgain = false
+			l.mu.Unlock()
+		}
+	}()
+}
+
+// fetch downloads the whole library, stores it and emits library:updated
+func (l *LibraryService) fetch() (*models.ReadingLists, error) {
+	lists, err := l.get()
+
+	l.mu.Lock()
+	if err != nil {
+		l.offline = httpclient.IsNetworkError(err)
+		l.lastError = err.Error()
+	} else {
+		l.offline = false
+		l.lastError = ""
+	}
+	l.mu.Unlock()
+
+	if err != nil {
+		stored, _ := l.store.load()
+		l.emitUpdated(stored)
+		return nil, err
+	}
+
+	// Changes not yet sent stay visible on top of the server's copy
+	for _, m := range l.queue.load().Mutations {
+		applyMutation(lists, m)
 	}
 
-	req, err := authRequest("GET", u.String(), nil)
+	fetchedAt := time.Now()
+	if err := l.store.save(*lists, fetchedAt); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to store library: %v", err))
+	}
+	l.emitUpdated(&storedLibrary{Lists: *lists, FetchedAt: fetchedAt})
+	return lists, nil
+}
+
+func (l *LibraryService) get() (*models.ReadingLists, error) {
+	req, err := l.client.NewAuthRequest("GET", l.base.URL()+"/users/library", nil)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf(string(b))
+		return nil, httpclient.ParseError(resp)
 	}
 
 	var list models.ReadingLists
 	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
 		return nil, err
 	}
-
 	return &list, nil
 }
 
+func (l *LibraryService) emitUpdated(stored *storedLibrary) {
+	if l.ctx == nil {
+		return
+	}
+	update := LibraryUpdate{State: l.state(stored)}
+	if stored != nil {
+		update.Lists = &stored.Lists
+	}
+	runtime.EventsEmit(l.ctx, "library:updated", update)
+}
+
 // ADD
 func (l *LibraryService) Add(mangaID, status string, chapter *int) error {
 	if mangaID == "" {
@@ -147,35 +324,37 @@ func (l *LibraryService) Add(mangaID, status string, chapter *int) error {
 		status = "plan_to_read"
 	}
 
+	_, _, err := l.send(Mutation{Kind: MutationAdd, MangaID: mangaID, Status: status, Chapter: chapter})
+	return err
+}
+
+func (l *LibraryService) add(m Mutation) error {
 	reqBody := map[string]interface{}{
-		"manga_id": mangaID,
-		"status":   status,
+		"manga_id": m.MangaID,
+		"status":   m.Status,
 	}
 
-	if chapter != nil {
-		reqBody["current_chapter"] = *chapter
+	if m.Chapter != nil {
+		reqBody["current_chapter"] = *m.Chapter
 	}
 
-	body, _ := json.Marshal(reqBody)
-
-	req, err := authRequest(
+	req, err := l.client.NewAuthRequest(
 		"POST",
-		l.BaseURL+"/users/library",
-		bytes.NewBuffer(body),
+		l.base.URL()+"/users/library",
+		reqBody,
 	)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("add failed: %s", string(b))
+		return httpclient.ParseError(resp)
 	}
 
 	return nil
@@ -187,31 +366,33 @@ func (l *LibraryService) Update(mangaID, status string) error {
 		return fmt.Errorf("manga_id and status required")
 	}
 
+	_, _, err := l.send(Mutation{Kind: MutationUpdate, MangaID: mangaID, Status: status})
+	return err
+}
+
+func (l *LibraryService) update(m Mutation) error {
 	reqBody := map[string]string{
-		"manga_id": mangaID,
-		"status":   status,
+		"manga_id": m.MangaID,
+		"status":   m.Status,
 	}
 
-	body, _ := json.Marshal(reqBody)
-
-	req, err := authRequest(
+	req, err := l.client.NewAuthRequest(
 		"PATCH",
-		l.BaseURL+"/users/library",
-		bytes.NewBuffer(body),
+		l.base.URL()+"/users/library",
+		reqBody,
 	)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("update failed: %s", string(b))
+		return httpclient.ParseError(resp)
 	}
 
 	return nil
@@ -223,58 +404,91 @@ func (l *LibraryService) Remove(mangaID string) error {
 		return fmt.Errorf("manga_id required")
 	}
 
+	_, _, err := l.send(Mutation{Kind: MutationRemove, MangaID: mangaID})
+	return err
+}
+
+func (l *LibraryService) remove(m Mutation) error {
 	reqBody := map[string]string{
-		"manga_id": mangaID,
+		"manga_id": m.MangaID,
 	}
 
-	body, _ := json.Marshal(reqBody)
-
-	req, err := authRequest(
+	req, err := l.client.NewAuthRequest(
 		"DELETE",
-		l.BaseURL+"/users/library",
-		bytes.NewBuffer(body),
+		l.base.URL()+"/users/library",
+		reqBody,
 	)
 	if err != nil {
 		return err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("remove failed: %s", string(b))
+		return httpclient.ParseError(resp)
 	}
 
 	return nil
 }
 
-func (l *LibraryService) SyncProgress() error {
-	jwt, err := utils.LoadToken()
-	if err != nil || jwt == "" {
-		return fmt.Errorf("not authenticated")
+// send delivers m to the server. It is queued instead while the server is
+// unreachable, or while earlier changes are still waiting so order is kept;
+// the queued mutation is then returned.
+func (l *LibraryService) send(m Mutation) (*ProgressUpdateResponse, *Mutation, error) {
+	waiting := l.queue.pending()
+	if !waiting {
+		result, err := l.apply(m)
+		if !httpclient.IsNetworkError(err) {
+			if err == nil {
+				l.refreshInBackground()
+			}
+			return result, nil, err
+		}
+	}
+
+	queued, err := l.enqueue(m)
+	if err != nil {
+		return nil, nil, err
 	}
+	if waiting {
+		go l.ReplayPending()
+	}
+	return nil, queued, nil
+}
 
-	url := l.BaseURL + "/users/progress/sync"
+// apply sends one change to the server
+func (l *LibraryService) apply(m Mutation) (*ProgressUpdateResponse, error) {
+	switch m.Kind {
+	case MutationAdd:
+		return nil, l.add(m)
+	case MutationUpdate:
+		return nil, l.update(m)
+	case MutationRemove:
+		return nil, l.remove(m)
+	case MutationProgress:
+		return l.updateProgress(m)
+	}
+	return nil, fmt.Errorf("unknown library change %q", m.Kind)
+}
 
-	req, err := http.NewRequest("POST", url, nil)
+func (l *LibraryService) SyncProgress() error {
+	req, err := l.client.NewAuthRequest("POST", l.base.URL()+"/users/progress/sync", nil)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", "Bearer "+jwt)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("sync failed: %s", string(body))
+		return httpclient.ParseError(resp)
 	}
 
 	return nil
@@ -282,28 +496,19 @@ func (l *LibraryService) SyncProgress() error {
 
 // GetSyncStatus checks the current sync status
 func (l *LibraryService) GetSyncStatus() (map[string]string, error) {
-	jwt, err := utils.LoadToken()
-	if err != nil || jwt == "" {
-		return nil, fmt.Errorf("not authenticated")
-	}
-
-	url := l.BaseURL + "/users/progress/sync-status"
-
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := l.client.NewAuthRequest("GET", l.base.URL()+"/users/progress/sync-status", nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+jwt)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed: %s", string(body))
+		return nil, httpclient.ParseError(resp)
 	}
 
 	var result map[string]string
@@ -327,32 +532,25 @@ type ProgressHistory struct {
 
 // GetProgressHistory fetches reading progress history
 func (l *LibraryService) GetProgressHistory(mangaID string) (*ProgressHistory, error) {
-	jwt, err := utils.LoadToken()
-	if err != nil || jwt == "" {
-		return nil, fmt.Errorf("not authenticated")
-	}
-
-	url := l.BaseURL + "/users/progress/history"
+	url := l.base.URL() + "/users/progress/history"
 	fmt.Println("URL:", url)
 	if mangaID != "" {
 		url += "?manga_id=" + mangaID
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := l.client.NewAuthRequest("GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+jwt)
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed: %s", string(body))
+		return nil, httpclient.ParseError(resp)
 	}
 
 	var result ProgressHistory
@@ -0,0 +1,574 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/httpclient"
+	"mangahub-desktop/backend/models"
+	"mangahub-desktop/backend/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// libraryQueueFile holds the active account's unsent library changes
+const libraryQueueFile = "library_queue.json"
+
+// queueRetryInterval is how often replay is retried while the server is down
+const queueRetryInterval = 30 * time.Second
+
+// Kinds of queued library changes
+const (
+	MutationAdd      = "add"
+	MutationUpdate   = "update"
+	MutationRemove   = "remove"
+	MutationProgress = "progress"
+)
+
+// Conflict policies, applied when the server changed an entry after a
+// change to it was queued
+const (
+	// ConflictAsk parks the change and emits library:conflict until the user
+	// calls ResolveConflict
+	ConflictAsk = "ask"
+	// ConflictKeepServer drops the queued change
+	ConflictKeepServer = "keep_server"
+	// ConflictKeepHighest keeps whichever chapter is further along; status
+	// changes and removals lose to the server's newer change. It never
+	// forces progress backwards.
+	ConflictKeepHighest = "keep_highest"
+)
+
+// Resolutions passed to ResolveConflict
+const (
+	ResolveKeepLocal  = "keep_local"
+	ResolveKeepServer = "keep_server"
+)
+
+// Mutation is one library change made while the server was unreachable
+type Mutation struct {
+	ID      string  `json:"id"`
+	Kind    string  `json:"kind"`
+	MangaID string  `json:"manga_id"`
+	Status  string  `json:"status,omitempty"`
+	Chapter *int    `json:"chapter,omitempty"`
+	Volume  *int    `json:"volume,omitempty"`
+	Notes   *string `json:"notes,omitempty"`
+	Force   bool    `json:"force,omitempty"`
+	// BaseChapter is the chapter the library showed when the change was
+	// made; the server being elsewhere means another device read on
+	BaseChapter *int      `json:"base_chapter,omitempty"`
+	QueuedAt    time.Time `json:"queued_at"`
+	// Conflict is set while the change waits for the user to decide
+	Conflict *Conflict `json:"conflict,omitempty"`
+}
+
+// Conflict describes how the server's entry differs from a queued change
+type Conflict struct {
+	MangaID       string    `json:"manga_id"`
+	Reason        string    `json:"reason"`
+	LocalChapter  int       `json:"local_chapter"`
+	ServerChapter int       `json:"server_chapter"`
+	LocalStatus   string    `json:"local_status,omitempty"`
+	ServerStatus  string    `json:"server_status,omitempty"`
+	ServerUpdated time.Time `json:"server_updated"`
+}
+
+// QueueState is the library:queue payload
+type QueueState struct {
+	Policy    string     `json:"policy"`
+	Mutations []Mutation `json:"mutations"`
+	// Failed lists changes the server rejected during the last replay
+	Failed []FailedMutation `json:"failed,omitempty"`
+}
+
+// FailedMutation is a queued change the server refused
+type FailedMutation struct {
+	Mutation Mutation `json:"mutation"`
+	Error    string   `json:"error"`
+}
+
+// storedQueue is the active account's library_queue.json
+type storedQueue struct {
+	Policy    string     `json:"policy"`
+	Mutations []Mutation `json:"mutations"`
+}
+
+// libraryQueue persists library changes, in order, until they reach the server
+type libraryQueue struct {
+	mu sync.Mutex
+}
+
+func (q *libraryQueue) read() (*storedQueue, error) {
+	stored := &storedQueue{}
+	if err := utils.LoadAccountJSON(libraryQueueFile, stored); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if stored.Policy == "" {
+		stored.Policy = ConflictAsk
+	}
+	return stored, nil
+}
+
+func (q *libraryQueue) write(stored *storedQueue) error {
+	return utils.SaveAccountJSON(libraryQueueFile, stored)
+}
+
+// load returns the queue; a missing or unreadable file is an empty queue
+func (q *libraryQueue) load() *storedQueue {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stored, err := q.read()
+	if err != nil {
+		utils.LogError(fmt.Sprintf("Failed to read library queue: %v", err))
+		return &storedQueue{Policy: ConflictAsk}
+	}
+	return stored
+}
+
+func (q *libraryQueue) pending() bool {
+	return len(q.load().Mutations) > 0
+}
+
+func (q *libraryQueue) push(m Mutation) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stored, err := q.read()
+	if err != nil {
+		return err
+	}
+	stored.Mutations = append(stored.Mutations, m)
+	return q.write(stored)
+}
+
+// replace swaps the mutation with the same ID for m
+func (q *libraryQueue) replace(m Mutation) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stored, err := q.read()
+	if err != nil {
+		return err
+	}
+	for i := range stored.Mutations {
+		if stored.Mutations[i].ID == m.ID {
+			stored.Mutations[i] = m
+			return q.write(stored)
+		}
+	}
+	return fmt.Errorf("queued change %s not found", m.ID)
+}
+
+func (q *libraryQueue) drop(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stored, err := q.read()
+	if err != nil {
+		return err
+	}
+	kept := stored.Mutations[:0]
+	for _, m := range stored.Mutations {
+		if m.ID != id {
+			kept = append(kept, m)
+		}
+	}
+	stored.Mutations = kept
+	return q.write(stored)
+}
+
+func (q *libraryQueue) setPolicy(policy string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stored, err := q.read()
+	if err != nil {
+		return err
+	}
+	stored.Policy = policy
+	return q.write(stored)
+}
+
+// findEntry returns the entry for mangaID in any list
+func findEntry(lists *models.ReadingLists, mangaID string) *models.ReadingEntry {
+	for _, list := range []*[]models.ReadingEntry{&lists.Reading, &lists.Completed, &lists.PlanToRead} {
+		for i := range *list {
+			if (*list)[i].MangaID == mangaID {
+				return &(*list)[i]
+			}
+		}
+	}
+	return nil
+}
+
+// listFor returns the list holding entries with status
+func listFor(lists *models.ReadingLists, status string) *[]models.ReadingEntry {
+	switch status {
+	case "reading":
+		return &lists.Reading
+	case "completed":
+		return &lists.Completed
+	case "plan_to_read":
+		return &lists.PlanToRead
+	}
+	return nil
+}
+
+// removeEntry takes mangaID out of every list and returns what was removed
+func removeEntry(lists *models.ReadingLists, mangaID string) *models.ReadingEntry {
+	var removed *models.ReadingEntry
+	for _, list := range []*[]models.ReadingEntry{&lists.Reading, &lists.Completed, &lists.PlanToRead} {
+		kept := (*list)[:0]
+		for _, e := range *list {
+			if e.MangaID == mangaID {
+				entry := e
+				removed = &entry
+				continue
+			}
+			kept = append(kept, e)
+		}
+		*list = kept
+	}
+	return removed
+}
+
+// applyMutation makes m's change to lists, as the server will once it is sent
+func applyMutation(lists *models.ReadingLists, m Mutation) {
+	switch m.Kind {
+	case MutationAdd, MutationUpdate:
+		entry := removeEntry(lists, m.MangaID)
+		if entry == nil {
+			entry = &models.ReadingEntry{MangaID: m.MangaID}
+		}
+		entry.Status = m.Status
+		if m.Chapter != nil {
+			entry.CurrentChapter = *m.Chapter
+		}
+		entry.LastUpdated = m.QueuedAt
+		if list := listFor(lists, m.Status); list != nil {
+			*list = append(*list, *entry)
+		}
+	case MutationRemove:
+		removeEntry(lists, m.MangaID)
+	case MutationProgress:
+		if entry := findEntry(lists, m.MangaID); entry != nil {
+			entry.CurrentChapter = *m.Chapter
+			if m.Volume != nil {
+				entry.Volume = m.Volume
+			}
+			if m.Notes != nil {
+				entry.Notes = m.Notes
+			}
+			entry.LastUpdated = m.QueuedAt
+		}
+	}
+}
+
+// detectConflict compares a queued change with the server's entry
+func detectConflict(m Mutation, server *models.ReadingEntry) *Conflict {
+	if server == nil {
+		return nil
+	}
+	conflict := &Conflict{
+		MangaID:       m.MangaID,
+		ServerChapter: server.CurrentChapter,
+		ServerStatus:  server.Status,
+		ServerUpdated: server.LastUpdated,
+		LocalStatus:   m.Status,
+	}
+	changedSince := server.LastUpdated.After(m.QueuedAt)
+
+	switch m.Kind {
+	case MutationProgress:
+		conflict.LocalChapter = *m.Chapter
+		if server.CurrentChapter == *m.Chapter || m.Force {
+			return nil
+		}
+		if m.BaseChapter != nil && server.CurrentChapter != *m.BaseChapter || m.BaseChapter == nil && changedSince {
+			conflict.Reason = "progress changed on another device"
+			return conflict
+		}
+	case MutationUpdate:
+		if server.Status != m.Status && changedSince {
+			conflict.Reason = "status changed on another device"
+			return conflict
+		}
+	case MutationRemove:
+		if changedSince {
+			conflict.Reason = "entry changed on another device after it was removed here"
+			return conflict
+		}
+	}
+	return nil
+}
+
+// keepLocal reports whether policy lets the queued change through.
+// Progress only goes through when it moves forward, so no Force is needed.
+func keepLocal(policy string, m Mutation, c *Conflict) bool {
+	if policy != ConflictKeepHighest {
+		return false
+	}
+	return m.Kind == MutationProgress && c.LocalChapter > c.ServerChapter
+}
+
+// isTransient reports whether err means "try again later" rather than the
+// server refusing the change
+func isTransient(err error) bool {
+	var apiErr *httpclient.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == httpclient.CodeNetwork || apiErr.Retryable || apiErr.Status == http.StatusUnauthorized
+}
+
+func isConflictStatus(err error) bool {
+	var apiErr *httpclient.APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict
+}
+
+// enqueue stores m to be sent later and shows it in the stored library
+func (l *LibraryService) enqueue(m Mutation) (*Mutation, error) {
+	now := time.Now()
+	m.ID = fmt.Sprintf("%d", now.UnixNano())
+	m.QueuedAt = now
+	if m.Kind == MutationProgress {
+		if stored, _ := l.store.load(); stored != nil {
+			if entry := findEntry(&stored.Lists, m.MangaID); entry != nil {
+				base := entry.CurrentChapter
+				m.BaseChapter = &base
+			}
+		}
+	}
+
+	if err := l.queue.push(m); err != nil {
+		return nil, err
+	}
+	if err := l.store.update(func(lists *models.ReadingLists) { applyMutation(lists, m) }); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to update stored library: %v", err))
+	}
+	utils.LogInfo(fmt.Sprintf("📥 Queued library %s for %s", m.Kind, m.MangaID))
+
+	stored, _ := l.store.load()
+	l.emitUpdated(stored)
+	l.emitQueue(nil)
+	l.scheduleReplay()
+	return &m, nil
+}
+
+// ReplayPending sends queued changes in order. It stops at the first one the
+// server cannot take yet, and at a conflict waiting for the user.
+func (l *LibraryService) ReplayPending() error {
+	l.mu.Lock()
+	if l.replaying {
+		l.replayAgain = true
+		l.mu.Unlock()
+		return nil
+	}
+	l.replaying = true
+	l.mu.Unlock()
+
+	for {
+		err := l.replay()
+
+		l.mu.Lock()
+		if err != nil || !l.replayAgain {
+			l.replaying = false
+			l.replayAgain = false
+			l.mu.Unlock()
+			return err
+		}
+		l.replayAgain = false
+		l.mu.Unlock()
+	}
+}
+
+func (l *LibraryService) replay() error {
+	if !l.queue.pending() {
+		return nil
+	}
+
+	// The server's library as it stands, kept current as changes go through
+	server, err := l.get()
+	if err != nil {
+		if isTransient(err) {
+			l.scheduleReplay()
+		}
+		return err
+	}
+
+	var failed []FailedMutation
+	sent := 0
+	for {
+		queue := l.queue.load()
+		if len(queue.Mutations) == 0 {
+			break
+		}
+		m := queue.Mutations[0]
+		if m.Conflict != nil {
+			l.emitConflict(m)
+			break
+		}
+
+		conflict := detectConflict(m, findEntry(server, m.MangaID))
+		if conflict != nil && !keepLocal(queue.Policy, m, conflict) {
+			if l.settle(queue.Policy, m, conflict) {
+				break
+			}
+			continue
+		}
+
+		_, err := l.apply(m)
+		if err != nil && m.Kind == MutationProgress && !m.Force && isConflictStatus(err) {
+			// The server refused to move progress backwards
+			conflict = &Conflict{MangaID: m.MangaID, Reason: err.Error(), LocalChapter: *m.Chapter}
+			if entry := findEntry(server, m.MangaID); entry != nil {
+				conflict.ServerChapter = entry.CurrentChapter
+				conflict.ServerStatus = entry.Status
+				conflict.ServerUpdated = entry.LastUpdated
+			}
+			if l.settle(queue.Policy, m, conflict) {
+				break
+			}
+			continue
+		}
+		if err != nil {
+			if isTransient(err) {
+				l.emitQueue(failed)
+				l.scheduleReplay()
+				return err
+			}
+			utils.LogError(fmt.Sprintf("📤 Server rejected queued library %s for %s: %v", m.Kind, m.MangaID, err))
+			failed = append(failed, FailedMutation{Mutation: m, Error: err.Error()})
+		} else {
+			applyMutation(server, m)
+			sent++
+		}
+		if err := l.queue.drop(m.ID); err != nil {
+			return err
+		}
+	}
+
+	if sent > 0 {
+		utils.LogInfo(fmt.Sprintf("📤 Sent %d queued library change(s)", sent))
+	}
+	l.emitQueue(failed)
+	_, err = l.fetch()
+	return err
+}
+
+// settle applies the conflict policy to m. It reports whether replay has to
+// stop because the user was asked.
+func (l *LibraryService) settle(policy string, m Mutation, conflict *Conflict) bool {
+	if policy == ConflictAsk {
+		m.Conflict = conflict
+		if err := l.queue.replace(m); err != nil {
+			utils.LogError(fmt.Sprintf("Failed to record library conflict: %v", err))
+		}
+		utils.LogInfo(fmt.Sprintf("⚠️ Library conflict on %s: %s", m.MangaID, conflict.Reason))
+		l.emitConflict(m)
+		return true
+	}
+
+	utils.LogInfo(fmt.Sprintf("⚠️ Library conflict on %s: %s, keeping the server's version", m.MangaID, conflict.Reason))
+	if err := l.queue.drop(m.ID); err != nil {
+		utils.LogError(fmt.Sprintf("Failed to drop queued library change: %v", err))
+	}
+	return false
+}
+
+// ResolveConflict answers a library:conflict. keep_local sends the queued
+// change anyway, forcing progress backwards if it has to; keep_server drops it.
+func (l *LibraryService) ResolveConflict(id, resolution string) error {
+	var m *Mutation
+	for _, queued := range l.queue.load().Mutations {
+		if queued.ID == id {
+			m = &queued
+			break
+		}
+	}
+	if m == nil || m.Conflict == nil {
+		return fmt.Errorf("no conflict %s waiting", id)
+	}
+
+	switch resolution {
+	case ResolveKeepLocal:
+		if m.Kind == MutationProgress && *m.Chapter < m.Conflict.ServerChapter {
+			m.Force = true
+		}
+		m.Conflict = nil
+		// Sent as is, without comparing against the server again
+		m.QueuedAt = time.Now()
+		m.BaseChapter = nil
+		if err := l.queue.replace(*m); err != nil {
+			return err
+		}
+	case ResolveKeepServer:
+		if err := l.queue.drop(m.ID); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown resolution %q", resolution)
+	}
+
+	l.emitQueue(nil)
+	go l.ReplayPending()
+	return nil
+}
+
+// GetQueueState returns the changes waiting to be sent
+func (l *LibraryService) GetQueueState() QueueState {
+	queue := l.queue.load()
+	return QueueState{Policy: queue.Policy, Mutations: queue.Mutations}
+}
+
+// SetConflictPolicy chooses how conflicts are settled for the active account
+func (l *LibraryService) SetConflictPolicy(policy string) error {
+	switch policy {
+	case ConflictAsk, ConflictKeepServer, ConflictKeepHighest:
+	default:
+		return fmt.Errorf("unknown conflict policy %q", policy)
+	}
+	if err := l.queue.setPolicy(policy); err != nil {
+		return err
+	}
+	l.emitQueue(nil)
+	return nil
+}
+
+// scheduleReplay retries replay later, for when nothing else reaches the
+// server to notice it is back
+func (l *LibraryService) scheduleReplay() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.retry != nil {
+		return
+	}
+	l.retry = time.AfterFunc(queueRetryInterval, func() {
+		l.mu.Lock()
+		l.retry = nil
+		l.mu.Unlock()
+		l.ReplayPending()
+	})
+}
+
+func (l *LibraryService) emitQueue(failed []FailedMutation) {
+	if l.ctx == nil {
+		return
+	}
+	state := l.GetQueueState()
+	state.Failed = failed
+	runtime.EventsEmit(l.ctx, "library:queue", state)
+}
+
+func (l *LibraryService) emitConflict(m Mutation) {
+	if l.ctx != nil {
+		runtime.EventsEmit(l.ctx, "library:conflict", m)
+	}
+}
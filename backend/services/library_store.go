@@ -0,0 +1,90 @@
+package services
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/models"
+	"mangahub-desktop/backend/utils"
+)
+
+// libraryStaleAfter is how old the stored library may get before the UI
+// marks it stale
+const libraryStaleAfter = 5 * time.Minute
+
+// storedLibrary is the active account's library.json
+type storedLibrary struct {
+	Lists     models.ReadingLists `json:"lists"`
+	FetchedAt time.Time           `json:"fetched_at"`
+}
+
+// libraryStore keeps the last library fetched from /users/library in the
+// active account's directory so it can be shown without the server
+type libraryStore struct {
+	mu sync.Mutex
+}
+
+// load returns the stored library, or nil when nothing was stored yet
+func (s *libraryStore) load() (*storedLibrary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read()
+}
+
+// save replaces the stored library with a fresh copy from the server
+func (s *libraryStore) save(lists models.ReadingLists, fetchedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return utils.SaveAccountJSON(libraryCacheFile, storedLibrary{Lists: lists, FetchedAt: fetchedAt})
+}
+
+// update changes the stored library in place, keeping its fetch time.
+// Nothing happens when no library was stored yet.
+func (s *libraryStore) update(fn func(*models.ReadingLists)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.read()
+	if err != nil || stored == nil {
+		return err
+	}
+	fn(&stored.Lists)
+	return utils.SaveAccountJSON(libraryCacheFile, stored)
+}
+
+func (s *libraryStore) read() (*storedLibrary, error) {
+	var stored storedLibrary
+	if err := utils.LoadAccountJSON(libraryCacheFile, &stored); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// Files written before the store had no timestamp and are refetched
+	if stored.FetchedAt.IsZero() {
+		return nil, nil
+	}
+	return &stored, nil
+}
+
+// filterLists returns only the list matching status, like the server does
+// for /users/library?status=
+func filterLists(lists models.ReadingLists, status string) *models.ReadingLists {
+	filtered := models.ReadingLists{
+		Reading:    []models.ReadingEntry{},
+		Completed:  []models.ReadingEntry{},
+		PlanToRead: []models.ReadingEntry{},
+	}
+	switch status {
+	case "":
+		return &lists
+	case "reading":
+		filtered.Reading = lists.Reading
+	case "completed":
+		filtered.Completed = lists.Completed
+	case "plan_to_read":
+		filtered.PlanToRead = lists.PlanToRead
+	}
+	return &filtered
+}
@@ -3,19 +3,21 @@ package services
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	neturl "net/url"
 	"strings"
 
+	"mangahub-desktop/backend/httpclient"
 	"mangahub-desktop/backend/models"
 )
 
 type MangaService struct {
-	BaseURL string
+	base   *Endpoint
+	client *httpclient.Client
 }
 
-func NewMangaService(baseURL string) *MangaService {
-	return &MangaService{BaseURL: baseURL}
+func NewMangaService(base *Endpoint, client *httpclient.Client) *MangaService {
+	return &MangaService{base: base, client: client}
 }
 
 func (l *MangaService) ListMangas(
@@ -32,7 +34,7 @@ func (l *MangaService) ListMangas(
 		joined := strings.Join(genres, ",")
 		url = fmt.Sprintf(
 			"%s/manga/filter/genre?query=%s&page=%d&page_size=%d",
-			l.BaseURL,
+			l.base.URL(),
 			joined,
 			page,
 			pageSize,
@@ -40,7 +42,7 @@ func (l *MangaService) ListMangas(
 	default:
 		url = fmt.Sprintf(
 			"%s/manga?page=%d&page_size=%d",
-			l.BaseURL,
+			l.base.URL(),
 			page,
 			pageSize,
 		)
@@ -51,20 +53,19 @@ func (l *MangaService) ListMangas(
 		url = fmt.Sprintf("%s&sort_by=%s", url, sortBy)
 	}
 
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	req, err := l.client.NewRequest(http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed %s: %s", resp.Status, string(body))
+		return nil, httpclient.ParseError(resp)
 	}
 
 	var response models.PaginatedMangasResponse
@@ -76,17 +77,21 @@ func (l *MangaService) ListMangas(
 }
 
 func (l *MangaService) ListMangaDetail(id string) (*models.Manga, error) {
-	url := fmt.Sprintf("%s/manga/%s", l.BaseURL, id)
+	url := fmt.Sprintf("%s/manga/%s", l.base.URL(), id)
 
-	resp, err := http.Get(url)
+	req, err := l.client.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("failed to load manga detail: %s", string(body))
+		return nil, httpclient.ParseError(resp)
 	}
 
 	var manga models.Manga
@@ -97,15 +102,18 @@ func (l *MangaService) ListMangaDetail(id string) (*models.Manga, error) {
 	return &manga, nil
 }
 func (l *MangaService) SearchMangas(query string) ([]models.Manga, error) {
-	url := fmt.Sprintf("%s/manga/search?query=%s", l.BaseURL, query)
-	resp, err := http.Get(url)
+	url := fmt.Sprintf("%s/manga/search?query=%s", l.base.URL(), neturl.QueryEscape(query))
+	req, err := l.client.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("search failed: %s", string(body))
+		return nil, httpclient.ParseError(resp)
 	}
 	var mangas []models.Manga
 	if err := json.NewDecoder(resp.Body).Decode(&mangas); err != nil {
@@ -18,12 +18,19 @@ type NotifyService struct {
 	syncService *SyncService
 	udpConn     *net.UDPConn
 	isRunning   bool
-	mu          sync.Mutex
+	// generation is bumped by Stop, so a listener that finishes starting
+	// afterwards knows it is no longer wanted
+	generation int
+	listenPort int
+	conns      *ConnectionManager
+	mu         sync.Mutex
 }
 
-func NewNotifyService(syncService *SyncService) *NotifyService {
+func NewNotifyService(syncService *SyncService, conns *ConnectionManager) *NotifyService {
 	return &NotifyService{
 		syncService: syncService,
+		listenPort:  3002,
+		conns:       conns,
 	}
 }
 
@@ -31,6 +38,15 @@ func (n *NotifyService) SetContext(ctx context.Context) {
 	n.ctx = ctx
 }
 
+// SetListenPort updates the local UDP port notifications are received on
+func (n *NotifyService) SetListenPort(port int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if port > 0 {
+		n.listenPort = port
+	}
+}
+
 func (n *NotifyService) Start() error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
@@ -46,58 +62,179 @@ func (n *NotifyService) Start() error {
 		return nil // silent fail for UI
 	}
 
-	// 🔍 Discover server via UDP
-	serverAddr, err := udpclient.DiscoverUDPServer(2 * time.Second)
+	// 🔍 Use the selected server, discovering one via UDP only if none is saved
+	serverAddr, err := utils.LoadUDPServerAddr()
 	if err != nil {
-		return err
+		serverAddr, err = udpclient.DiscoverUDPServer(2 * time.Second)
+		if err != nil {
+			return err
+		}
+		_ = utils.SaveUDPServerAddr(serverAddr)
 	}
-	_ = utils.SaveUDPServerAddr(serverAddr)
 
 	// 📡 Register for UDP notifications
+	n.conns.connecting(TransportNotify, serverAddr)
 	if err := udpclient.RegisterUDPNotification(serverAddr, jwt); err != nil {
+		n.conns.failed(TransportNotify, serverAddr, err)
 		return err
 	}
 
+	// The server may not know the stored subscriptions, e.g. after a
+	// profile switch to another server
+	go n.resubscribe(serverAddr, jwt)
+
 	// 👂 Start UDP listener (background)
+	listenPort := n.listenPort
+	generation := n.generation
 	go func() {
-		conn, err := udpclient.StartUDPListenerWithHandler(3002, func(noti udpclient.Notification) {
+		conn, err := udpclient.StartUDPListenerWithHandler(listenPort, func(noti udpclient.Notification) {
 			runtime.EventsEmit(n.ctx, "notify:manga", noti)
 		})
 		if err != nil {
 			log.Printf("UDP listener error: %v", err)
+			n.conns.failed(TransportNotify, serverAddr, err)
+			return
+		}
+
+		n.mu.Lock()
+		if n.generation != generation {
+			// Stopped while the listener was starting
+			n.mu.Unlock()
+			conn.Close()
 			return
 		}
 		n.udpConn = conn
-		log.Println("✅ UDP listener started on port 3002")
+		n.mu.Unlock()
+
+		n.conns.connected(TransportNotify, serverAddr)
+		log.Printf("✅ UDP listener started on port %d", listenPort)
 	}()
 
 	n.isRunning = true
 	log.Println("NotifyService started, ctx ready:", n.ctx != nil)
 
-	if err != nil {
-		log.Printf("Failed to load server IP addr: %v", err)
-	} else {
-		// 🔗 Auto-start TCP sync service
-		if n.syncService != nil {
-			go func() {
-				// Small delay to ensure UDP listener is fully started
-				time.Sleep(100 * time.Millisecond)
-
-				if err := n.syncService.StartAutoConnect(); err != nil {
-					log.Printf("Failed to auto-start TCP sync: %v\n", err)
-				}
-			}()
-		}
+	// 🔗 Auto-start TCP sync service
+	if n.syncService != nil {
+		go func() {
+			// Small delay to ensure UDP listener is fully started
+			time.Sleep(100 * time.Millisecond)
+
+			if err := n.syncService.StartAutoConnect(); err != nil {
+				log.Printf("Failed to auto-start TCP sync: %v\n", err)
+			}
+		}()
 	}
 
 	return nil
 }
 
+// subscriptionsFile holds the active account's manga subscriptions
+const subscriptionsFile = "subscriptions.json"
+
 func (n *NotifyService) Subscribe(mangaID string) error {
 	jwt, _ := utils.LoadToken()
 	addr, _ := utils.LoadUDPServerAddr()
 
-	return udpclient.SubscribeMangaUDP(addr, jwt, mangaID)
+	if err := udpclient.SubscribeMangaUDP(addr, jwt, mangaID); err != nil {
+		return err
+	}
+
+	subscriptions := n.GetSubscriptions()
+	for _, id := range subscriptions {
+		if id == mangaID {
+			return nil
+		}
+	}
+	if err := utils.SaveAccountJSON(subscriptionsFile, append(subscriptions, mangaID)); err != nil {
+		log.Printf("Failed to save subscriptions: %v", err)
+	}
+	return nil
+}
+
+// Unsubscribe stops notifications for a manga
+func (n *NotifyService) Unsubscribe(mangaID string) error {
+	jwt, _ := utils.LoadToken()
+	addr, _ := utils.LoadUDPServerAddr()
+
+	if err := udpclient.UnsubscribeMangaUDP(addr, jwt, mangaID); err != nil {
+		return err
+	}
+
+	subscriptions := n.GetSubscriptions()
+	kept := subscriptions[:0]
+	for _, id := range subscriptions {
+		if id != mangaID {
+			kept = append(kept, id)
+		}
+	}
+	if err := utils.SaveAccountJSON(subscriptionsFile, kept); err != nil {
+		log.Printf("Failed to save subscriptions: %v", err)
+	}
+	return nil
+}
+
+// resubscribe repeats the account's stored subscriptions to the server
+func (n *NotifyService) resubscribe(serverAddr, jwt string) {
+	for _, mangaID := range n.GetSubscriptions() {
+		if err := udpclient.SubscribeMangaUDP(serverAddr, jwt, mangaID); err != nil {
+			log.Printf("Failed to resubscribe to %s: %v", mangaID, err)
+		}
+	}
+}
+
+// Unregister drops this client's subscriptions and registration on the
+// server and stops listening, as on logout. Failures are logged: the server
+// may already be gone, and logging out must still succeed.
+func (n *NotifyService) Unregister() {
+	jwt, _ := utils.LoadToken()
+	addr, err := utils.LoadUDPServerAddr()
+	if jwt != "" && err == nil {
+		for _, mangaID := range n.GetSubscriptions() {
+			if err := udpclient.UnsubscribeMangaUDP(addr, jwt, mangaID); err != nil {
+				log.Printf("Failed to unsubscribe from %s: %v", mangaID, err)
+			}
+		}
+	}
+	n.Leave()
+}
+
+// Leave drops this client's registration on the server and stops listening,
+// keeping the account's subscriptions for when it registers again
+func (n *NotifyService) Leave() {
+	jwt, _ := utils.LoadToken()
+	addr, err := utils.LoadUDPServerAddr()
+	if jwt != "" && err == nil {
+		if err := udpclient.UnregisterUDPNotification(addr, jwt); err != nil {
+			log.Printf("Failed to unregister notifications: %v", err)
+		}
+	}
+	n.Stop()
+}
+
+// GetSubscriptions returns the manga IDs the active account subscribed to
+func (n *NotifyService) GetSubscriptions() []string {
+	var subscriptions []string
+	if err := utils.LoadAccountJSON(subscriptionsFile, &subscriptions); err != nil {
+		return []string{}
+	}
+	return subscriptions
+}
+
+// IsRunning reports whether notifications are registered and listening
+func (n *NotifyService) IsRunning() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.isRunning
+}
+
+// Restart re-registers with the server, restarts the UDP listener and
+// reconnects TCP sync, e.g. after the local IP changed
+func (n *NotifyService) Restart() error {
+	n.Stop()
+	if n.syncService != nil {
+		n.syncService.Stop()
+	}
+	return n.Start()
 }
 
 // Stop closes the UDP listener connection
@@ -107,7 +244,10 @@ func (n *NotifyService) Stop() {
 
 	if n.udpConn != nil {
 		n.udpConn.Close()
+		n.udpConn = nil
 		log.Println("UDP listener closed")
 	}
+	n.generation++
 	n.isRunning = false
+	n.conns.disconnected(TransportNotify)
 }
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/httpclient"
+	"mangahub-desktop/backend/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// refreshLead is how long before expiry the token is renewed
+	refreshLead = 5 * time.Minute
+	// refreshRetry is the delay between renewal attempts while offline
+	refreshRetry = 30 * time.Second
+)
+
+// SessionInfo describes the stored session
+type SessionInfo struct {
+	LoggedIn  bool      `json:"logged_in"`
+	Username  string    `json:"username,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Expired   bool      `json:"expired"`
+}
+
+// SessionEvent is emitted as auth:expiring, auth:refreshed and auth:expired
+type SessionEvent struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// SessionService renews the token before it expires and, once it cannot be
+// renewed, pauses the realtime services and tells the frontend to log in again
+type SessionService struct {
+	ctx       context.Context
+	mu        sync.Mutex
+	auth      *AuthService
+	chat      *ChatService
+	sync      *SyncService
+	notify    *NotifyService
+	timer     *time.Timer
+	expiresAt time.Time
+	active    bool
+	expired   bool
+
+	OnExpired func() // Callback run after the services were paused
+}
+
+func NewSessionService(auth *AuthService, chat *ChatService, sync *SyncService, notify *NotifyService) *SessionService {
+	return &SessionService{
+		auth:   auth,
+		chat:   chat,
+		sync:   sync,
+		notify: notify,
+	}
+}
+
+func (s *SessionService) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// Start manages the stored token, scheduling its renewal
+func (s *SessionService) Start() error {
+	token, err := utils.LoadToken()
+	if err != nil || token == "" {
+		return fmt.Errorf("not logged in")
+	}
+	claims, err := utils.ParseTokenUnverified(token)
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+
+	s.mu.Lock()
+	s.active = true
+	s.expired = false
+	s.mu.Unlock()
+
+	if claims.ExpiresAt == nil {
+		utils.LogInfo("🔑 Token has no expiry, nothing to schedule")
+		return nil
+	}
+	if !claims.ExpiresAt.After(time.Now()) {
+		s.Expire("token expired")
+		return fmt.Errorf("token expired")
+	}
+
+	s.schedule(claims)
+	return nil
+}
+
+// Stop ends session management without emitting anything, e.g. on logout
+func (s *SessionService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active = false
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
+
+// Refresh renews the token now
+func (s *SessionService) Refresh() error {
+	if err := s.auth.RefreshToken(); err != nil {
+		return err
+	}
+
+	token, _ := utils.LoadToken()
+	claims, err := utils.ParseTokenUnverified(token)
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+
+	s.mu.Lock()
+	active := s.active
+	s.mu.Unlock()
+
+	if claims.ExpiresAt != nil {
+		utils.LogInfo(fmt.Sprintf("🔑 Token renewed, valid until %s", claims.ExpiresAt.Time.Format(time.RFC3339)))
+		s.emit("auth:refreshed", SessionEvent{ExpiresAt: claims.ExpiresAt.Time})
+		if active {
+			s.schedule(claims)
+		}
+	}
+	return nil
+}
+
+// GetSession returns what the stored token says about the session
+func (s *SessionService) GetSession() SessionInfo {
+	token, err := utils.LoadToken()
+	if err != nil || token == "" {
+		return SessionInfo{}
+	}
+	claims, err := utils.ParseTokenUnverified(token)
+	if err != nil {
+		return SessionInfo{}
+	}
+
+	info := SessionInfo{LoggedIn: true, Username: claims.Username}
+	if claims.ExpiresAt != nil {
+		info.ExpiresAt = claims.ExpiresAt.Time
+		info.Expired = !claims.ExpiresAt.After(time.Now())
+	}
+	return info
+}
+
+// Expire pauses sync, chat and notifications and emits auth:expired once
+func (s *SessionService) Expire(reason string) {
+	s.mu.Lock()
+	if s.expired {
+		s.mu.Unlock()
+		return
+	}
+	s.expired = true
+	s.active = false
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	expiresAt := s.expiresAt
+	s.mu.Unlock()
+
+	utils.LogInfo(fmt.Sprintf("🔒 Session expired (%s), pausing realtime services", reason))
+
+	s.notify.Stop()
+	s.sync.Stop()
+	s.chat.Disconnect()
+	if s.OnExpired != nil {
+		s.OnExpired()
+	}
+
+	s.emit("auth:expired", SessionEvent{ExpiresAt: expiresAt, Error: reason})
+}
+
+// schedule arms the renewal timer ahead of the token's expiry. Short-lived
+// tokens are renewed after four fifths of their lifetime instead.
+func (s *SessionService) schedule(claims *utils.SignedDetails) {
+	expiresAt := claims.ExpiresAt.Time
+	refreshAt := expiresAt.Add(-refreshLead)
+	if claims.IssuedAt != nil {
+		lifetime := expiresAt.Sub(claims.IssuedAt.Time)
+		if lifetime < 2*refreshLead {
+			refreshAt = claims.IssuedAt.Time.Add(lifetime * 4 / 5)
+		}
+	}
+
+	s.mu.Lock()
+	s.expiresAt = expiresAt
+	s.arm(time.Until(refreshAt), s.renew)
+	s.mu.Unlock()
+
+	utils.LogInfo(fmt.Sprintf("🔑 Token renewal scheduled for %s", refreshAt.Format(time.RFC3339)))
+}
+
+// arm replaces the pending timer; callers hold s.mu
+func (s *SessionService) arm(d time.Duration, fn func()) {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if d < 0 {
+		d = 0
+	}
+	s.timer = time.AfterFunc(d, fn)
+}
+
+// renew is the timer callback: it retries while offline and, if the server
+// refuses to renew, lets the token run out
+func (s *SessionService) renew() {
+	s.mu.Lock()
+	if !s.active {
+		s.mu.Unlock()
+		return
+	}
+	expiresAt := s.expiresAt
+	s.mu.Unlock()
+
+	if token, _ := utils.LoadToken(); token == "" {
+		// Logged out meanwhile
+		s.Stop()
+		return
+	}
+
+	err := s.Refresh()
+	if err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	active := s.active
+	s.mu.Unlock()
+	if !active {
+		// Expired or logged out while renewing
+		return
+	}
+
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		s.Expire("token expired")
+		return
+	}
+
+	utils.LogError(fmt.Sprintf("🔑 Token renewal failed, %s left: %v", remaining.Round(time.Second), err))
+	s.emit("auth:expiring", SessionEvent{ExpiresAt: expiresAt, Error: err.Error()})
+
+	var apiErr *httpclient.APIError
+	retryable := errors.As(err, &apiErr) && apiErr.Retryable
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.active {
+		return
+	}
+	if retryable && remaining > refreshRetry {
+		s.arm(refreshRetry, s.renew)
+		return
+	}
+	s.arm(remaining, func() { s.Expire("token expired") })
+}
+
+func (s *SessionService) emit(event string, data SessionEvent) {
+	if s.ctx != nil {
+		runtime.EventsEmit(s.ctx, event, data)
+	}
+}
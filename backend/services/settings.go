@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"sync"
+
+	"mangahub-desktop/backend/config"
+	grpcclient "mangahub-desktop/backend/grpc-client"
+	"mangahub-desktop/backend/transport"
+	"mangahub-desktop/backend/udpclient"
+	"mangahub-desktop/backend/utils"
+)
+
+type SettingsService struct {
+	cfg      *config.Config
+	rest     *Endpoint
+	identity *IdentityService
+	chat     *ChatService
+	sync     *SyncService
+	notify   *NotifyService
+	applied  config.Profile
+	mu       sync.Mutex
+
+	OnProfileSwitch func() error // Callback to re-run discovery for the new profile
+}
+
+func NewSettingsService(
+	cfg *config.Config,
+	rest *Endpoint,
+	identity *IdentityService,
+	chat *ChatService,
+	sync *SyncService,
+	notify *NotifyService,
+) *SettingsService {
+	return &SettingsService{
+		cfg:      cfg,
+		rest:     rest,
+		identity: identity,
+		chat:     chat,
+		sync:     sync,
+		notify:   notify,
+	}
+}
+
+// ListProfiles returns all stored server profiles
+func (s *SettingsService) ListProfiles() []config.Profile {
+	return s.cfg.List()
+}
+
+// GetActiveProfile returns the profile currently in use (with env overrides)
+func (s *SettingsService) GetActiveProfile() config.Profile {
+	return s.cfg.Active()
+}
+
+// AddProfile stores a new profile, replacing any profile with the same name
+func (s *SettingsService) AddProfile(profile config.Profile) error {
+	if _, err := transport.BuildTLSConfig(profile.TLS); err != nil {
+		return err
+	}
+	if err := transport.ValidateProxy(profile.Proxy); err != nil {
+		return err
+	}
+	if err := s.cfg.Upsert(profile); err != nil {
+		return err
+	}
+	return s.cfg.Save()
+}
+
+// RemoveProfile deletes a stored profile
+func (s *SettingsService) RemoveProfile(name string) error {
+	if err := s.cfg.Remove(name); err != nil {
+		return err
+	}
+	return s.cfg.Save()
+}
+
+// SwitchProfile makes the named profile active and rebinds every service to
+// it. Notifications, sync and chat leave the previous server first and are
+// reconnected to the new one if they were in use.
+func (s *SettingsService) SwitchProfile(name string) error {
+	if err := s.cfg.SetActive(name); err != nil {
+		return err
+	}
+	if err := s.cfg.Save(); err != nil {
+		return err
+	}
+
+	room := s.chat.GetCurrentRoom()
+	wasRunning := s.notify.IsRunning() || s.sync.IsRunning()
+
+	// Leave while the previous server is still the selected one; the stored
+	// subscriptions are sent again once notifications restart
+	s.notify.Leave()
+	s.sync.Stop()
+	s.chat.Disconnect()
+
+	// Forget the server picked under the previous profile
+	if err := utils.ClearSelectedServer(); err != nil {
+		return err
+	}
+
+	profile := s.cfg.Active()
+	if err := s.ApplyProfile(profile); err != nil {
+		return err
+	}
+
+	if s.OnProfileSwitch != nil {
+		if err := s.OnProfileSwitch(); err != nil {
+			return err
+		}
+	}
+
+	// Starting notifications also reconnects TCP sync
+	if wasRunning {
+		if err := s.notify.Start(); err != nil {
+			utils.LogError(fmt.Sprintf("Failed to restart notifications on profile %q: %v", profile.Name, err))
+		}
+	}
+	if room != "" {
+		if err := s.chat.SwitchRoom(room); err != nil {
+			utils.LogError(fmt.Sprintf("Failed to rejoin chat room %s on profile %q: %v", room, profile.Name, err))
+		}
+	}
+	return nil
+}
+
+// GetAppliedProfile returns the endpoints the services are currently bound to,
+// i.e. the active profile resolved against the discovered server
+func (s *SettingsService) GetAppliedProfile() config.Profile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.applied
+}
+
+// ApplyProfile pushes the profile endpoints into all services. When the
+// profile's CA file or pins cannot be loaded the endpoints are still applied,
+// so nothing keeps talking to the previous server, but every TLS connection
+// is refused and the error is returned.
+func (s *SettingsService) ApplyProfile(profile config.Profile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tlsErr := transport.Configure(profile.TLS)
+	if tlsErr != nil {
+		utils.LogError(fmt.Sprintf("🔒 Invalid TLS settings for profile %q, refusing TLS connections: %v", profile.Name, tlsErr))
+		tlsErr = fmt.Errorf("invalid TLS settings for profile %q: %w", profile.Name, tlsErr)
+	}
+	if err := transport.ConfigureProxy(profile.Proxy); err != nil {
+		utils.LogError(fmt.Sprintf("🌐 Invalid proxy settings for profile %q: %v", profile.Name, err))
+	}
+
+	s.applied = profile
+	s.rest.Set(profile.HTTPBase)
+	s.identity.Invalidate()
+	s.chat.SetBaseURL(profile.WSBase)
+	s.sync.SetBaseURL(profile.Host())
+	s.sync.SetPort(profile.SyncPort)
+	s.notify.SetListenPort(profile.NotifyPort)
+	udpclient.SetDiscoveryPort(profile.DiscoveryPort)
+	grpcclient.SetHost(profile.Host())
+	grpcclient.SetPort(profile.GRPCPort)
+
+	utils.LogInfo(fmt.Sprintf("📍 Applied profile %q: %s", profile.Name, profile.HTTPBase))
+	return tlsErr
+}
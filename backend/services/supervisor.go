@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/utils"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+const (
+	// supervisorInterval is how often the current server is health-checked
+	supervisorInterval = 15 * time.Second
+	// failureThreshold is how many consecutive failed checks trigger a failover
+	failureThreshold = 2
+)
+
+// FailoverEvent is emitted as connection:failover while recovering
+type FailoverEvent struct {
+	State string `json:"state"` // started | completed | failed
+	Room  string `json:"room,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Supervisor watches the server and, once it is lost, rediscovers a server
+// and rebinds every service before restoring the chat room and sync session
+type Supervisor struct {
+	ctx      context.Context
+	mu       sync.Mutex
+	conns    *ConnectionManager
+	chat     *ChatService
+	sync     *SyncService
+	notify   *NotifyService
+	cancel   context.CancelFunc
+	trigger  chan struct{}
+	failures int
+
+	Check      func() error // Callback probing the currently bound server
+	Rediscover func() error // Callback re-running discovery and rebinding all services
+}
+
+func NewSupervisor(conns *ConnectionManager, chat *ChatService, sync *SyncService, notify *NotifyService) *Supervisor {
+	s := &Supervisor{
+		conns:   conns,
+		chat:    chat,
+		sync:    sync,
+		notify:  notify,
+		trigger: make(chan struct{}, 1),
+	}
+
+	// A transport dropping is a hint the server may be gone - check right away
+	conns.onTransition(func(status TransportStatus) {
+		if status.State != StateError {
+			return
+		}
+		switch status.Transport {
+		case TransportREST, TransportSync, TransportWebSocket:
+			s.Trigger()
+		}
+	})
+
+	return s
+}
+
+func (s *Supervisor) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// Start begins supervising; calling it while running is a no-op
+func (s *Supervisor) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil || s.ctx == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.cancel = cancel
+	s.failures = 0
+	go s.loop(ctx)
+
+	utils.LogInfo("🛡️ Server supervisor started")
+}
+
+// Stop ends supervision
+func (s *Supervisor) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+		s.cancel = nil
+		utils.LogInfo("🛡️ Server supervisor stopped")
+	}
+}
+
+// Trigger requests an immediate health check
+func (s *Supervisor) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Supervisor) loop(ctx context.Context) {
+	ticker := time.NewTicker(supervisorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.trigger:
+		}
+
+		if s.Check == nil {
+			continue
+		}
+
+		if err := s.Check(); err != nil {
+			s.failures++
+			utils.LogError(fmt.Sprintf("🛡️ Server check failed (%d/%d): %v", s.failures, failureThreshold, err))
+			if s.failures < failureThreshold {
+				// Re-check soon rather than waiting a full interval
+				time.AfterFunc(2*time.Second, s.Trigger)
+				continue
+			}
+			s.failover(ctx)
+			continue
+		}
+		s.failures = 0
+	}
+}
+
+// failover tears the transports down, rebinds everything to a newly
+// discovered server and re-establishes the sessions that were active
+func (s *Supervisor) failover(ctx context.Context) {
+	room := s.chat.GetCurrentRoom()
+	syncWasRunning := s.sync.IsRunning()
+
+	utils.LogInfo(fmt.Sprintf("🔁 Server lost, failing over (room=%q, sync=%v)", room, syncWasRunning))
+	s.emit(FailoverEvent{State: "started", Room: room})
+
+	s.notify.Stop()
+	s.sync.Stop()
+	s.chat.Disconnect()
+
+	if s.Rediscover != nil {
+		if err := s.Rediscover(); err != nil {
+			utils.LogError(fmt.Sprintf("🔁 Failover rediscovery failed: %v", err))
+			s.emit(FailoverEvent{State: "failed", Room: room, Error: err.Error()})
+			return
+		}
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Re-register for UDP notifications; this also restarts TCP sync
+	var failures []error
+	if err := s.notify.Start(); err != nil {
+		utils.LogError(fmt.Sprintf("🔁 Failed to restart notifications: %v", err))
+		failures = append(failures, fmt.Errorf("notifications: %w", err))
+		if syncWasRunning {
+			if err := s.sync.StartAutoConnect(); err != nil {
+				utils.LogError(fmt.Sprintf("🔁 Failed to restart sync: %v", err))
+				failures = append(failures, fmt.Errorf("sync: %w", err))
+			}
+		}
+	}
+
+	if room != "" {
+		if err := s.chat.SwitchRoom(room); err != nil {
+			utils.LogError(fmt.Sprintf("🔁 Failed to rejoin chat room %s: %v", room, err))
+			failures = append(failures, fmt.Errorf("chat room %s: %w", room, err))
+		}
+	}
+
+	if err := errors.Join(failures...); err != nil {
+		utils.LogError(fmt.Sprintf("🔁 Failover incomplete: %v", err))
+		s.emit(FailoverEvent{State: "failed", Room: room, Error: err.Error()})
+		return
+	}
+
+	s.failures = 0
+	utils.LogInfo("🔁 Failover completed")
+	s.emit(FailoverEvent{State: "completed", Room: room})
+}
+
+func (s *Supervisor) emit(event FailoverEvent) {
+	if s.ctx != nil {
+		runtime.EventsEmit(s.ctx, "connection:failover", event)
+	}
+}
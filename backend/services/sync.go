@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"mangahub-desktop/backend/transport"
 	"mangahub-desktop/backend/utils"
 	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -20,6 +22,8 @@ type SyncService struct {
 	cancelFunc context.CancelFunc
 	deviceID   string
 	baseURL    string
+	port       int
+	conns      *ConnectionManager
 }
 
 type ProgressBroadcast struct {
@@ -34,9 +38,11 @@ type ProgressBroadcast struct {
 	ReadingStreak     int       `json:"reading_streak"`
 }
 
-func NewSyncService() *SyncService {
+func NewSyncService(conns *ConnectionManager) *SyncService {
 	return &SyncService{
 		deviceID: generateDeviceID(),
+		port:     9090,
+		conns:    conns,
 	}
 }
 
@@ -50,6 +56,15 @@ func (s *SyncService) SetBaseURL(baseURL string) {
 	utils.LogInfo(fmt.Sprintf("Sync service updated to use: %s", baseURL))
 }
 
+// SetPort updates the TCP sync port
+func (s *SyncService) SetPort(port int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if port > 0 {
+		s.port = port
+	}
+}
+
 func generateDeviceID() string {
 	return fmt.Sprintf("device-%d", time.Now().UnixNano()%1000000)
 }
@@ -69,14 +84,20 @@ func (s *SyncService) StartAutoConnect() error {
 		return fmt.Errorf("not authenticated")
 	}
 
+	// Each account keeps its own device ID across restarts
+	s.deviceID = utils.AccountDeviceID()
+
 	// Create cancellable context
 	ctx, cancel := context.WithCancel(s.ctx)
 	s.cancelFunc = cancel
 
-	// Connect to TCP server on port 9090
-	conn, err := net.DialTimeout("tcp", s.baseURL+":9090", 10*time.Second)
+	// Connect to TCP sync server
+	addr := net.JoinHostPort(s.baseURL, strconv.Itoa(s.port))
+	s.conns.connecting(TransportSync, addr)
+	conn, err := transport.DialTCP(addr, 10*time.Second)
 	if err != nil {
 		cancel()
+		s.conns.failed(TransportSync, addr, err)
 		fmt.Printf("Failed to connect to sync server: %v\n", err)
 		return err
 	}
@@ -94,18 +115,27 @@ func (s *SyncService) StartAutoConnect() error {
 		conn.Close()
 		s.isRunning = false
 		cancel()
+		s.conns.failed(TransportSync, addr, err)
 		fmt.Printf("Handshake failed: %v\n", err)
 		return err
 	}
 
 	fmt.Printf("TCP sync connected to %s with device ID: %s\n", s.baseURL, s.deviceID)
+	s.conns.connected(TransportSync, addr)
 
 	// Start listening for broadcasts in background
-	go s.listen(ctx)
+	go s.listen(ctx, conn)
 
 	return nil
 }
 
+// IsRunning reports whether the TCP sync connection is up
+func (s *SyncService) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.isRunning
+}
+
 // Stop disconnects from the sync server
 func (s *SyncService) Stop() error {
 	s.mu.Lock()
@@ -121,27 +151,31 @@ func (s *SyncService) Stop() error {
 
 	if s.conn != nil {
 		s.conn.Close()
+		s.conn = nil
 	}
 
 	s.isRunning = false
+	s.conns.disconnected(TransportSync)
 	fmt.Println("TCP sync disconnected")
 
 	return nil
 }
 
-// listen continuously reads broadcast messages from TCP connection
-func (s *SyncService) listen(ctx context.Context) {
+// listen continuously reads broadcast messages from conn. A restart may
+// have replaced conn by the time it exits, so only its own state is reset.
+func (s *SyncService) listen(ctx context.Context, conn net.Conn) {
 	defer func() {
+		conn.Close()
 		s.mu.Lock()
-		if s.conn != nil {
-			s.conn.Close()
+		if s.conn == conn {
+			s.conn = nil
+			s.isRunning = false
 		}
-		s.isRunning = false
 		s.mu.Unlock()
 		fmt.Println("TCP listener stopped")
 	}()
 
-	decoder := json.NewDecoder(s.conn)
+	decoder := json.NewDecoder(conn)
 
 	for {
 		select {
@@ -149,7 +183,7 @@ func (s *SyncService) listen(ctx context.Context) {
 			return
 		default:
 			// Set read deadline
-			s.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+			conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 
 			var broadcast ProgressBroadcast
 			if err := decoder.Decode(&broadcast); err != nil {
@@ -158,8 +192,11 @@ func (s *SyncService) listen(ctx context.Context) {
 					continue
 				}
 
-				// Connection error
+				// Connection error (unless we are shutting down)
 				fmt.Printf("TCP connection error: %v\n", err)
+				if ctx.Err() == nil {
+					s.conns.failed(TransportSync, "", err)
+				}
 				return
 			}
 
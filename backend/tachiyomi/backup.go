@@ -0,0 +1,235 @@
+// Package tachiyomi reads the library out of Tachiyomi and Mihon backups
+// (.tachibk, older .proto.gz): gzipped protobuf, decoded field by field so
+// fields added by newer app versions are simply skipped.
+package tachiyomi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers from the app's Backup, BackupManga, BackupChapter and
+// BackupTracking messages
+const (
+	backupMangaField = 1
+
+	mangaSource   = 1
+	mangaURL      = 2
+	mangaTitle    = 3
+	mangaArtist   = 4
+	mangaAuthor   = 5
+	mangaChapters = 16
+	mangaTracking = 18
+	mangaFavorite = 100
+
+	chapterURL          = 1
+	chapterName         = 2
+	chapterRead         = 4
+	chapterLastPageRead = 6
+	chapterNumber       = 9
+
+	trackingTitle           = 5
+	trackingLastChapterRead = 6
+)
+
+// Backup is the part of a backup describing the library
+type Backup struct {
+	Manga []Manga
+}
+
+// Manga is one series in the backup
+type Manga struct {
+	Source int64
+	URL    string
+	Title  string
+	Artist string
+	Author string
+	// Favorite is false for series only kept for their history
+	Favorite bool
+	Chapters []Chapter
+	Tracking []Tracking
+}
+
+// Chapter is a chapter the app knew of
+type Chapter struct {
+	URL          string
+	Name         string
+	Read         bool
+	LastPageRead int64
+	// Number is the parsed chapter number, negative when unknown
+	Number float32
+}
+
+// Tracking is the series' state on a tracker such as MyAnimeList
+type Tracking struct {
+	Title           string
+	LastChapterRead float32
+}
+
+// LastRead returns the highest chapter number marked read, falling back to
+// the trackers' progress; 0 when nothing was read
+func (m Manga) LastRead() float64 {
+	last := 0.0
+	for _, c := range m.Chapters {
+		if c.Read && c.Number > 0 {
+			last = math.Max(last, float64(c.Number))
+		}
+	}
+	if last > 0 {
+		return last
+	}
+	for _, t := range m.Tracking {
+		last = math.Max(last, float64(t.LastChapterRead))
+	}
+	return last
+}
+
+// Decode reads a backup, decompressing it first if it is gzipped
+func Decode(r io.Reader) (*Backup, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip data: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &Backup{}
+	err = fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num != backupMangaField || typ != protowire.BytesType {
+			return nil
+		}
+		manga, err := decodeManga(value)
+		if err != nil {
+			return err
+		}
+		backup.Manga = append(backup.Manga, manga)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("not a Tachiyomi backup: %w", err)
+	}
+	return backup, nil
+}
+
+func decodeManga(data []byte) (Manga, error) {
+	// Favorite defaults to true and is then left out of the encoding
+	m := Manga{Favorite: true}
+	err := fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == mangaSource && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(value)
+			m.Source = int64(v)
+		case num == mangaURL && typ == protowire.BytesType:
+			m.URL = string(value)
+		case num == mangaTitle && typ == protowire.BytesType:
+			m.Title = string(value)
+		case num == mangaArtist && typ == protowire.BytesType:
+			m.Artist = string(value)
+		case num == mangaAuthor && typ == protowire.BytesType:
+			m.Author = string(value)
+		case num == mangaFavorite && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(value)
+			m.Favorite = v != 0
+		case num == mangaChapters && typ == protowire.BytesType:
+			c, err := decodeChapter(value)
+			if err != nil {
+				return err
+			}
+			m.Chapters = append(m.Chapters, c)
+		case num == mangaTracking && typ == protowire.BytesType:
+			t, err := decodeTracking(value)
+			if err != nil {
+				return err
+			}
+			m.Tracking = append(m.Tracking, t)
+		}
+		return nil
+	})
+	return m, err
+}
+
+func decodeChapter(data []byte) (Chapter, error) {
+	c := Chapter{Number: -1}
+	err := fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == chapterURL && typ == protowire.BytesType:
+			c.URL = string(value)
+		case num == chapterName && typ == protowire.BytesType:
+			c.Name = string(value)
+		case num == chapterRead && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(value)
+			c.Read = v != 0
+		case num == chapterLastPageRead && typ == protowire.VarintType:
+			v, _ := protowire.ConsumeVarint(value)
+			c.LastPageRead = int64(v)
+		case num == chapterNumber && typ == protowire.Fixed32Type:
+			v, _ := protowire.ConsumeFixed32(value)
+			c.Number = math.Float32frombits(v)
+		}
+		return nil
+	})
+	return c, err
+}
+
+func decodeTracking(data []byte) (Tracking, error) {
+	var t Tracking
+	err := fields(data, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		switch {
+		case num == trackingTitle && typ == protowire.BytesType:
+			t.Title = string(value)
+		case num == trackingLastChapterRead && typ == protowire.Fixed32Type:
+			v, _ := protowire.ConsumeFixed32(value)
+			t.LastChapterRead = math.Float32frombits(v)
+		}
+		return nil
+	})
+	return t, err
+}
+
+// fields calls fn for every field of a message. value holds the raw varint
+// or fixed bytes, or the payload of a length-delimited field.
+func fields(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		var value []byte
+		if typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value, data = v, data[n:]
+		} else {
+			n := protowire.ConsumeFieldValue(num, typ, data)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			value, data = data[:n], data[n:]
+		}
+
+		if err := fn(num, typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
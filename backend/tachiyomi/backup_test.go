@@ -0,0 +1,149 @@
+package tachiyomi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+func appendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendFloat(b []byte, num protowire.Number, f float32) []byte {
+	b = protowire.AppendTag(b, num, protowire.Fixed32Type)
+	return protowire.AppendFixed32(b, math.Float32bits(f))
+}
+
+func chapter(number float32, read bool) []byte {
+	var c []byte
+	c = appendString(c, chapterURL, "/chapter")
+	if read {
+		c = appendVarint(c, chapterRead, 1)
+	}
+	// Chapter numbers of -1 mean unknown and are left out like any default
+	if number >= 0 {
+		c = appendFloat(c, chapterNumber, number)
+	}
+	return c
+}
+
+// backupFile builds a gzipped backup the way the app writes one, with
+// fields this package does not know mixed in
+func backupFile(t *testing.T) []byte {
+	t.Helper()
+
+	// Read up to 12.5; chapter 20 is known but unread
+	var berserk []byte
+	berserk = appendVarint(berserk, mangaSource, 2499283573021220255)
+	berserk = appendString(berserk, mangaURL, "/manga/berserk")
+	berserk = appendString(berserk, mangaTitle, "Berserk")
+	berserk = appendString(berserk, mangaAuthor, "Kentaro Miura")
+	berserk = appendVarint(berserk, 13, 1700000000000) // dateAdded
+	berserk = appendMessage(berserk, mangaChapters, chapter(12.5, true))
+	berserk = appendMessage(berserk, mangaChapters, chapter(3, true))
+	berserk = appendMessage(berserk, mangaChapters, chapter(20, false))
+	berserk = protowire.AppendTag(berserk, 99, protowire.Fixed64Type)
+	berserk = protowire.AppendFixed64(berserk, 7)
+
+	// Only in history, with progress known from a tracker alone
+	var tracking []byte
+	tracking = appendVarint(tracking, 1, 1) // syncId
+	tracking = appendString(tracking, trackingTitle, "Monster")
+	tracking = appendFloat(tracking, trackingLastChapterRead, 40)
+	var monster []byte
+	monster = appendString(monster, mangaTitle, "Monster")
+	monster = appendVarint(monster, mangaFavorite, 0)
+	monster = appendMessage(monster, mangaChapters, chapter(-1, true))
+	monster = appendMessage(monster, mangaTracking, tracking)
+
+	var backup []byte
+	backup = appendMessage(backup, backupMangaField, berserk)
+	backup = appendMessage(backup, 2, appendString(nil, 1, "Favourites")) // backupCategories
+	backup = appendMessage(backup, backupMangaField, monster)
+	backup = appendVarint(backup, 500, 3)
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(backup); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	backup, err := Decode(bytes.NewReader(backupFile(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup.Manga) != 2 {
+		t.Fatalf("got %d manga, want 2", len(backup.Manga))
+	}
+
+	berserk := backup.Manga[0]
+	if berserk.Title != "Berserk" || berserk.Author != "Kentaro Miura" || berserk.URL != "/manga/berserk" {
+		t.Errorf("berserk = %+v", berserk)
+	}
+	if berserk.Source != 2499283573021220255 {
+		t.Errorf("source = %d", berserk.Source)
+	}
+	if !berserk.Favorite {
+		t.Error("Favorite left out of the encoding should default to true")
+	}
+	if len(berserk.Chapters) != 3 || !berserk.Chapters[0].Read || berserk.Chapters[2].Read {
+		t.Errorf("chapters = %+v", berserk.Chapters)
+	}
+	if got := berserk.LastRead(); got != 12.5 {
+		t.Errorf("LastRead() = %v, want 12.5", got)
+	}
+
+	monster := backup.Manga[1]
+	if monster.Favorite {
+		t.Error("Favorite = false was not decoded")
+	}
+	if monster.Chapters[0].Number != -1 {
+		t.Errorf("missing chapter number = %v, want -1", monster.Chapters[0].Number)
+	}
+	if len(monster.Tracking) != 1 || monster.Tracking[0].Title != "Monster" {
+		t.Errorf("tracking = %+v", monster.Tracking)
+	}
+	if got := monster.LastRead(); got != 40 {
+		t.Errorf("LastRead() = %v, want the tracker's 40", got)
+	}
+}
+
+func TestDecodeUncompressed(t *testing.T) {
+	raw := appendMessage(nil, backupMangaField, appendString(nil, mangaTitle, "Vagabond"))
+	backup, err := Decode(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup.Manga) != 1 || backup.Manga[0].Title != "Vagabond" || backup.Manga[0].LastRead() != 0 {
+		t.Errorf("backup = %+v", backup)
+	}
+}
+
+func TestDecodeRejectsGarbage(t *testing.T) {
+	// A length-delimited field claiming more bytes than there are
+	garbage := append(protowire.AppendTag(nil, backupMangaField, protowire.BytesType), 0x7f, 0x01)
+	if _, err := Decode(bytes.NewReader(garbage)); err == nil {
+		t.Error("Decode accepted a truncated message")
+	}
+}
@@ -0,0 +1,193 @@
+package transport
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"mangahub-desktop/backend/config"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// proxyFunc picks the proxy for a target URL; nil means connect directly
+type proxyFunc func(*url.URL) (*url.URL, error)
+
+var proxyFor proxyFunc = httpproxy.FromEnvironment().ProxyFunc()
+
+// ConfigureProxy applies a profile's proxy settings to every transport
+func ConfigureProxy(settings config.Proxy) error {
+	fn, err := buildProxyFunc(settings)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	proxyFor = fn
+	old := httpNext
+	httpNext = newHTTPTransport(tlsConfig)
+	mu.Unlock()
+
+	if t, ok := old.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	return nil
+}
+
+// ValidateProxy checks proxy settings without applying them
+func ValidateProxy(settings config.Proxy) error {
+	_, err := buildProxyFunc(settings)
+	return err
+}
+
+func buildProxyFunc(settings config.Proxy) (proxyFunc, error) {
+	if settings.Disabled {
+		return func(*url.URL) (*url.URL, error) { return nil, nil }, nil
+	}
+	if settings.URL == "" {
+		env := httpproxy.FromEnvironment()
+		if settings.NoProxy != "" {
+			env.NoProxy = settings.NoProxy
+		}
+		return env.ProxyFunc(), nil
+	}
+
+	u, err := url.Parse(settings.URL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q", settings.URL)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+
+	cfg := &httpproxy.Config{
+		HTTPProxy:  settings.URL,
+		HTTPSProxy: settings.URL,
+		NoProxy:    settings.NoProxy,
+	}
+	return cfg.ProxyFunc(), nil
+}
+
+// proxyForRequest is the http.Transport hook
+func proxyForRequest(req *http.Request) (*url.URL, error) {
+	mu.RLock()
+	fn := proxyFor
+	mu.RUnlock()
+	return fn(req.URL)
+}
+
+// dialContext connects to addr, through the proxy chosen for scheme. Raw TCP
+// (sync, gRPC) uses the https proxy, as CONNECT tunnels are what proxies
+// generally allow for non-HTTP traffic.
+func dialContext(ctx context.Context, scheme, addr string) (net.Conn, error) {
+	mu.RLock()
+	fn := proxyFor
+	mu.RUnlock()
+
+	proxyURL, err := fn(&url.URL{Scheme: scheme, Host: addr})
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	if proxyURL == nil {
+		return d.DialContext(ctx, "tcp", addr)
+	}
+
+	switch proxyURL.Scheme {
+	case "socks5", "socks5h":
+		dialer, err := proxy.FromURL(proxyURL, &d)
+		if err != nil {
+			return nil, err
+		}
+		if cd, ok := dialer.(proxy.ContextDialer); ok {
+			return cd.DialContext(ctx, "tcp", addr)
+		}
+		return dialer.Dial("tcp", addr)
+	default:
+		return dialConnect(ctx, proxyURL, addr)
+	}
+}
+
+// dialConnect opens an HTTP CONNECT tunnel to addr through an HTTP(S) proxy
+func dialConnect(ctx context.Context, proxyURL *url.URL, addr string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		port := "80"
+		if proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(proxyURL.Hostname(), port)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", proxyAddr, err)
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("proxy %s: %w", proxyAddr, err)
+		}
+		conn = tlsConn
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %w", addr, err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %w", addr, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT %s: %s", addr, resp.Status)
+	}
+
+	if br.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn replays bytes the proxy sent right after its CONNECT reply
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
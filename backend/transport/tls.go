@@ -0,0 +1,86 @@
+package transport
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+
+	"mangahub-desktop/backend/config"
+)
+
+// BuildTLSConfig turns a profile's TLS settings into a client tls.Config.
+// It returns nil when TLS is disabled.
+func BuildTLSConfig(settings config.TLS) (*tls.Config, error) {
+	if !settings.Enabled {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: settings.ServerName,
+	}
+
+	if settings.CAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", settings.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if len(settings.Pins) > 0 {
+		pins, err := parsePins(settings.Pins)
+		if err != nil {
+			return nil, err
+		}
+		// Runs after the chain was verified, so pinning only narrows trust
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, cert := range cs.PeerCertificates {
+				if pins[sha256.Sum256(cert.RawSubjectPublicKeyInfo)] {
+					return nil
+				}
+			}
+			return fmt.Errorf("server certificate does not match any pinned key")
+		}
+	}
+
+	return cfg, nil
+}
+
+// SPKIPin returns the pin string for a certificate, as accepted in config.TLS.Pins
+func SPKIPin(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func parsePins(raw []string) (map[[sha256.Size]byte]bool, error) {
+	pins := make(map[[sha256.Size]byte]bool, len(raw))
+	for _, pin := range raw {
+		pin = strings.TrimPrefix(strings.TrimSpace(pin), "sha256/")
+		if pin == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid certificate pin %q: expected base64 SHA-256", pin)
+		}
+		var sum [sha256.Size]byte
+		copy(sum[:], decoded)
+		pins[sum] = true
+	}
+	if len(pins) == 0 {
+		return nil, fmt.Errorf("no valid certificate pins")
+	}
+	return pins, nil
+}
@@ -0,0 +1,167 @@
+// Package transport holds the connection settings of the applied profile and
+// hands out dialers for REST, WebSocket, gRPC and TCP sync built from them.
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/config"
+
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var (
+	mu        sync.RWMutex
+	tlsConfig *tls.Config       // nil while TLS is disabled
+	httpNext  http.RoundTripper = newHTTPTransport(nil)
+)
+
+// Configure applies a profile's TLS settings to every transport. Connections
+// already open keep their settings; new ones use the new configuration.
+// Settings that cannot be loaded fail closed: every TLS handshake is refused
+// until valid settings are applied, rather than trusting less than asked.
+func Configure(settings config.TLS) error {
+	cfg, err := BuildTLSConfig(settings)
+	if err != nil {
+		cfg = refusingTLSConfig(err)
+	}
+
+	mu.Lock()
+	old := httpNext
+	tlsConfig = cfg
+	httpNext = newHTTPTransport(cfg)
+	mu.Unlock()
+
+	if t, ok := old.(*http.Transport); ok {
+		t.CloseIdleConnections()
+	}
+	return err
+}
+
+// refusingTLSConfig keeps TLS on for every transport but fails each
+// handshake with the reason the configured settings were rejected
+func refusingTLSConfig(reason error) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		VerifyConnection: func(tls.ConnectionState) error {
+			return fmt.Errorf("TLS settings rejected: %w", reason)
+		},
+	}
+}
+
+// Enabled reports whether TLS is in use for the applied profile
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return tlsConfig != nil
+}
+
+// TLSConfig returns a copy of the applied TLS config, or nil when disabled
+func TLSConfig() *tls.Config {
+	mu.RLock()
+	defer mu.RUnlock()
+	if tlsConfig == nil {
+		return nil
+	}
+	return tlsConfig.Clone()
+}
+
+// HTTPTransport returns a RoundTripper that always uses the applied settings
+func HTTPTransport() http.RoundTripper {
+	return roundTripper{}
+}
+
+type roundTripper struct{}
+
+func (roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	mu.RLock()
+	next := httpNext
+	mu.RUnlock()
+	return next.RoundTrip(req)
+}
+
+func newHTTPTransport(cfg *tls.Config) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = cfg
+	t.Proxy = proxyForRequest
+	return t
+}
+
+// DialWebSocket connects to a ws:// or wss:// URL
+func DialWebSocket(rawURL string, header http.Header) (*websocket.Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	scheme := "http"
+	if u.Scheme == "wss" {
+		scheme = "https"
+	}
+
+	dialer := &websocket.Dialer{
+		HandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:  TLSConfig(),
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return dialContext(ctx, scheme, addr)
+		},
+	}
+	return dialer.Dial(rawURL, header)
+}
+
+// GRPCTarget returns the target to pass to grpc.NewClient for addr. The
+// passthrough resolver hands the host name to the dialer, so proxies can
+// resolve it themselves.
+func GRPCTarget(addr string) string {
+	return "passthrough:///" + addr
+}
+
+// GRPCDialOptions returns the credentials and proxy-aware dialer for gRPC;
+// TLS credentials are used when enabled, insecure ones otherwise
+func GRPCDialOptions() []grpc.DialOption {
+	creds := insecure.NewCredentials()
+	if cfg := TLSConfig(); cfg != nil {
+		creds = credentials.NewTLS(cfg)
+	}
+	return []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialContext(ctx, "https", addr)
+		}),
+	}
+}
+
+// DialTCP opens a raw TCP connection (the sync protocol) through the proxy,
+// wrapped in TLS when enabled. The handshake completes before it returns.
+func DialTCP(addr string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conn, err := dialContext(ctx, "https", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := TLSConfig()
+	if cfg == nil {
+		return conn, nil
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+	tlsConn := tls.Client(conn, cfg)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
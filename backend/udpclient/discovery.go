@@ -0,0 +1,430 @@
+package udpclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mangahub-desktop/backend/utils"
+)
+
+var (
+	portMu sync.Mutex
+	// discoveryUDPPort is the UDP port servers listen on for DISCOVER_MANGAHUB
+	discoveryUDPPort = 9091
+)
+
+// SetDiscoveryPort changes the port discovery broadcasts are sent to
+func SetDiscoveryPort(port int) {
+	portMu.Lock()
+	defer portMu.Unlock()
+	if port > 0 {
+		discoveryUDPPort = port
+	}
+}
+
+// discoveryPort returns the port set by SetDiscoveryPort
+func discoveryPort() int {
+	portMu.Lock()
+	defer portMu.Unlock()
+	return discoveryUDPPort
+}
+
+type DiscoverResponse struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// DiscoveredServer is a single MANGAHUB_OFFER collected during discovery
+type DiscoveredServer struct {
+	Name      string `json:"name"`
+	Host      string `json:"host"`
+	Port      int    `json:"port"`
+	Addr      string `json:"addr"`
+	LatencyMs int64  `json:"latency_ms"`
+	// Interface is the local interface the offer arrived on, LocalIP our address there
+	Interface string `json:"interface,omitempty"`
+	LocalIP   string `json:"local_ip,omitempty"`
+	// Service ports advertised by the server; zero means use the profile default
+	HTTPPort int    `json:"http_port,omitempty"`
+	SyncPort int    `json:"sync_port,omitempty"`
+	GRPCPort int    `json:"grpc_port,omitempty"`
+	Source   string `json:"source"` // broadcast | mdns
+}
+
+// Discovery strategies
+const (
+	// StrategyAuto runs mDNS and the UDP broadcast together and merges the results
+	StrategyAuto = "auto"
+	// StrategyMDNS tries mDNS first and falls back to the UDP broadcast
+	StrategyMDNS = "mdns"
+	// StrategyBroadcast tries the UDP broadcast first and falls back to mDNS
+	StrategyBroadcast = "broadcast"
+)
+
+// ifaceAddr is one address assigned to an up, non-loopback interface
+type ifaceAddr struct {
+	name  string
+	ipnet *net.IPNet
+}
+
+type offer struct {
+	server DiscoveredServer
+	err    error
+}
+
+func DiscoverUDPServer(timeout time.Duration) (string, error) {
+	servers, err := discover(timeout, true)
+	if err != nil {
+		return "", err
+	}
+	return servers[0].Addr, nil
+}
+
+// DiscoverUDPServers broadcasts a discovery request and collects every offer
+// received until the timeout expires. Offers are deduplicated by host/port and
+// sorted by round-trip latency, fastest first.
+func DiscoverUDPServers(timeout time.Duration) ([]DiscoveredServer, error) {
+	return discover(timeout, false)
+}
+
+// Discover finds MangaHub servers using the given strategy. Sequential
+// strategies give the primary method two thirds of the timeout.
+func Discover(strategy string, timeout time.Duration) ([]DiscoveredServer, error) {
+	switch strategy {
+	case StrategyMDNS:
+		return discoverWithFallback(DiscoverMDNS, DiscoverUDPServers, timeout)
+	case StrategyBroadcast:
+		return discoverWithFallback(DiscoverUDPServers, DiscoverMDNS, timeout)
+	}
+
+	type result struct {
+		servers []DiscoveredServer
+		err     error
+	}
+	results := make(chan result, 2)
+	for _, fn := range []func(time.Duration) ([]DiscoveredServer, error){DiscoverMDNS, DiscoverUDPServers} {
+		go func(fn func(time.Duration) ([]DiscoveredServer, error)) {
+			servers, err := fn(timeout)
+			results <- result{servers, err}
+		}(fn)
+	}
+
+	var merged []DiscoveredServer
+	var errs []string
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err != nil {
+			errs = append(errs, r.err.Error())
+			continue
+		}
+		merged = append(merged, r.servers...)
+	}
+	if len(merged) == 0 {
+		return nil, fmt.Errorf("discovery failed: %s", strings.Join(errs, "; "))
+	}
+
+	return dedupeServers(merged), nil
+}
+
+func discoverWithFallback(
+	primary, fallback func(time.Duration) ([]DiscoveredServer, error),
+	timeout time.Duration,
+) ([]DiscoveredServer, error) {
+	primaryTimeout := timeout * 2 / 3
+
+	servers, err := primary(primaryTimeout)
+	if err == nil {
+		return servers, nil
+	}
+	fmt.Printf("⚠️ %v, falling back\n", err)
+
+	servers, fallbackErr := fallback(timeout - primaryTimeout)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("%v; %v", err, fallbackErr)
+	}
+	return servers, nil
+}
+
+// dedupeServers keeps the fastest offer per host/port, sorted by latency.
+// mDNS offers carry more port information, so they win ties.
+func dedupeServers(servers []DiscoveredServer) []DiscoveredServer {
+	sort.SliceStable(servers, func(i, j int) bool {
+		if servers[i].LatencyMs != servers[j].LatencyMs {
+			return servers[i].LatencyMs < servers[j].LatencyMs
+		}
+		return servers[i].Source == "mdns" && servers[j].Source != "mdns"
+	})
+
+	seen := map[string]bool{}
+	result := make([]DiscoveredServer, 0, len(servers))
+	for _, s := range servers {
+		if seen[s.Addr] {
+			continue
+		}
+		seen[s.Addr] = true
+		result = append(result, s)
+	}
+	return result
+}
+
+// discover sends a directed broadcast on every IPv4 subnet plus an IPv6
+// link-local multicast probe per interface, then gathers MANGAHUB_OFFERs.
+func discover(timeout time.Duration, firstOnly bool) ([]DiscoveredServer, error) {
+	discoveryMsg := map[string]string{
+		"type":    "DISCOVER_MANGAHUB",
+		"action":  "",
+		"token":   "",
+		"payload": "",
+	}
+	body, _ := json.Marshal(discoveryMsg)
+
+	addrs := localInterfaceAddrs()
+
+	conn4, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn4.Close()
+
+	// IPv6 is optional - many hosts have it disabled
+	conn6, err := net.ListenUDP("udp6", nil)
+	if err != nil {
+		conn6 = nil
+	} else {
+		defer conn6.Close()
+	}
+
+	sentAt := time.Now()
+	deadline := sentAt.Add(timeout)
+	sent := 0
+
+	for _, target := range broadcastTargets(addrs) {
+		if _, err := conn4.WriteToUDP(body, target); err == nil {
+			sent++
+		}
+	}
+	if conn6 != nil {
+		for _, target := range multicastTargets() {
+			if _, err := conn6.WriteToUDP(body, target); err == nil {
+				sent++
+			}
+		}
+	}
+	if sent == 0 {
+		return nil, fmt.Errorf("failed to send UDP discovery probe on any interface")
+	}
+
+	fmt.Printf("🔍 Sent %d UDP discovery probes, collecting offers...\n", sent)
+
+	offers := make(chan offer, 16)
+	var wg sync.WaitGroup
+	for _, conn := range []*net.UDPConn{conn4, conn6} {
+		if conn == nil {
+			continue
+		}
+		conn.SetReadDeadline(deadline)
+		wg.Add(1)
+		go func(conn *net.UDPConn) {
+			defer wg.Done()
+			readOffers(conn, sentAt, addrs, offers)
+		}(conn)
+	}
+	go func() {
+		wg.Wait()
+		close(offers)
+	}()
+
+	seen := make(map[string]bool)
+	servers := []DiscoveredServer{}
+
+	for o := range offers {
+		if o.err != nil {
+			fmt.Println("⚠️", o.err)
+			continue
+		}
+		if seen[o.server.Addr] {
+			continue
+		}
+		seen[o.server.Addr] = true
+		servers = append(servers, o.server)
+
+		fmt.Printf("✅ Discovered UDP server: %s (%s) via %s in %dms\n",
+			o.server.Name, o.server.Addr, o.server.Interface, o.server.LatencyMs)
+
+		if firstOnly {
+			// Unblock the readers so they can exit
+			conn4.SetReadDeadline(time.Now())
+			if conn6 != nil {
+				conn6.SetReadDeadline(time.Now())
+			}
+		}
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no UDP server discovered")
+	}
+
+	sort.SliceStable(servers, func(i, j int) bool {
+		return servers[i].LatencyMs < servers[j].LatencyMs
+	})
+
+	return servers, nil
+}
+
+func readOffers(conn *net.UDPConn, sentAt time.Time, addrs []ifaceAddr, offers chan<- offer) {
+	buffer := make([]byte, 2048)
+	for {
+		n, from, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			// Deadline reached (or socket closed) - stop collecting
+			return
+		}
+		latency := time.Since(sentAt)
+
+		var resp DiscoverResponse
+		if err := json.Unmarshal(buffer[:n], &resp); err != nil {
+			continue
+		}
+		if resp.Type != "MANGAHUB_OFFER" {
+			offers <- offer{err: fmt.Errorf("invalid discovery response from %s", from)}
+			continue
+		}
+
+		iface, localIP := interfaceFor(from, addrs)
+		server := DiscoveredServer{
+			Name:      resp.Name,
+			Host:      resp.Host,
+			Port:      resp.Port,
+			Addr:      net.JoinHostPort(resp.Host, fmt.Sprint(resp.Port)),
+			LatencyMs: latency.Milliseconds(),
+			Interface: iface,
+			Source:    "broadcast",
+		}
+		if localIP != nil {
+			server.LocalIP = localIP.String()
+		}
+		offers <- offer{server: server}
+	}
+}
+
+// localInterfaceAddrs lists addresses of interfaces that are up and not loopback
+func localInterfaceAddrs() []ifaceAddr {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var result []ifaceAddr
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipnet, ok := a.(*net.IPNet); ok {
+				result = append(result, ifaceAddr{name: iface.Name, ipnet: ipnet})
+			}
+		}
+	}
+	return result
+}
+
+// broadcastTargets returns the directed broadcast address of every IPv4
+// subnet, plus the limited broadcast as a last resort
+func broadcastTargets(addrs []ifaceAddr) []*net.UDPAddr {
+	seen := map[string]bool{}
+	var targets []*net.UDPAddr
+
+	add := func(ip net.IP) {
+		if seen[ip.String()] {
+			return
+		}
+		seen[ip.String()] = true
+		targets = append(targets, &net.UDPAddr{IP: ip, Port: discoveryPort()})
+	}
+
+	for _, a := range addrs {
+		ip4 := a.ipnet.IP.To4()
+		if ip4 == nil {
+			continue
+		}
+		mask := a.ipnet.Mask
+		if len(mask) == net.IPv6len {
+			mask = mask[12:]
+		}
+		if len(mask) != net.IPv4len {
+			continue
+		}
+		bcast := make(net.IP, net.IPv4len)
+		for i := range ip4 {
+			bcast[i] = ip4[i] | ^mask[i]
+		}
+		add(bcast)
+	}
+	add(net.IPv4bcast)
+
+	return targets
+}
+
+// multicastTargets returns the all-nodes link-local multicast address scoped
+// to each multicast-capable interface
+func multicastTargets() []*net.UDPAddr {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var targets []*net.UDPAddr
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 ||
+			iface.Flags&net.FlagLoopback != 0 ||
+			iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		targets = append(targets, &net.UDPAddr{
+			IP:   net.IPv6linklocalallnodes,
+			Port: discoveryPort(),
+			Zone: iface.Name,
+		})
+	}
+	return targets
+}
+
+// interfaceFor works out which local interface a packet from src arrived on
+func interfaceFor(src *net.UDPAddr, addrs []ifaceAddr) (string, net.IP) {
+	if src.Zone != "" {
+		for _, a := range addrs {
+			if a.name == src.Zone && a.ipnet.IP.To4() == nil {
+				return a.name, a.ipnet.IP
+			}
+		}
+		return src.Zone, nil
+	}
+
+	for _, a := range addrs {
+		if a.ipnet.Contains(src.IP) {
+			return a.name, a.ipnet.IP
+		}
+	}
+
+	// Routed (non on-link) server: ask the kernel which source it would use
+	if ip := utils.GetReplyIP(src); ip != nil {
+		for _, a := range addrs {
+			if a.ipnet.IP.Equal(ip) {
+				return a.name, ip
+			}
+		}
+		return "", ip
+	}
+	return "", nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 )
 
@@ -12,64 +13,39 @@ type Notification struct {
 	Chapter   int64
 	Timestamp time.Time
 }
-type DiscoverResponse struct {
-	Type string `json:"type"`
-	Name string `json:"name"`
-	Host string `json:"host"`
-	Port int    `json:"port"`
-}
 
-func DiscoverUDPServer(timeout time.Duration) (string, error) {
-	discoveryMsg := map[string]string{
-		"type":    "DISCOVER_MANGAHUB",
-		"action":  "",
-		"token":   "",
-		"payload": "",
-	}
-	body, _ := json.Marshal(discoveryMsg)
-
-	broadcastAddr, err := net.ResolveUDPAddr("udp", "255.255.255.255:9091")
-	if err != nil {
-		return "", err
-	}
-
-	conn, err := net.ListenUDP("udp", nil)
-	if err != nil {
-		return "", err
-	}
-	defer conn.Close()
-
-	// Enable broadcast
-	conn.SetWriteBuffer(1024)
-
-	_, err = conn.WriteToUDP(body, broadcastAddr)
-	if err != nil {
-		return "", err
-	}
-
-	fmt.Println("🔍 Sent UDP discovery broadcast")
+var (
+	localMu   sync.Mutex
+	localAddr *net.UDPAddr
+)
 
-	buffer := make([]byte, 2048)
-	conn.SetReadDeadline(time.Now().Add(timeout))
+// SetLocalIP binds follow-up UDP traffic (registration, subscriptions) to the
+// local address discovery reached the server on. An empty ip clears the binding.
+func SetLocalIP(ip string) {
+	localMu.Lock()
+	defer localMu.Unlock()
 
-	n, addr, err := conn.ReadFromUDP(buffer)
-	if err != nil {
-		return "", fmt.Errorf("no UDP server discovered")
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		localAddr = nil
+		return
 	}
+	localAddr = &net.UDPAddr{IP: parsed}
+}
 
-	var resp DiscoverResponse
-	if err := json.Unmarshal(buffer[:n], &resp); err != nil {
-		return "", err
-	}
+// dialServer connects to the server from the bound local address, falling
+// back to the kernel's choice if that address is no longer available
+func dialServer(serverAddr *net.UDPAddr) (*net.UDPConn, error) {
+	localMu.Lock()
+	laddr := localAddr
+	localMu.Unlock()
 
-	if resp.Type != "MANGAHUB_OFFER" {
-		return "", fmt.Errorf("invalid discovery response from %s", addr)
+	if laddr != nil {
+		if conn, err := net.DialUDP("udp", laddr, serverAddr); err == nil {
+			return conn, nil
+		}
 	}
-
-	serverAddr := fmt.Sprintf("%s:%d", resp.Host, resp.Port)
-	fmt.Printf("✅ Discovered UDP server: %s (%s)\n", resp.Name, serverAddr)
-
-	return serverAddr, nil
+	return net.DialUDP("udp", nil, serverAddr)
 }
 
 type UDPResponse struct {
@@ -92,7 +68,7 @@ func RegisterUDPNotification(serverAddr string, jwt string) error {
 
 	body, _ := json.Marshal(data)
 
-	conn, err := net.DialUDP("udp", nil, udpAddr)
+	conn, err := dialServer(udpAddr)
 	if err != nil {
 		return err
 	}
@@ -143,7 +119,7 @@ func SubscribeMangaUDP(serverAddr string, jwt string, mangaID string) error {
 		"payload": mangaID,
 	}
 	body, _ := json.Marshal(data)
-	conn, err := net.DialUDP("udp", nil, serverAddress)
+	conn, err := dialServer(serverAddress)
 	if err != nil {
 		return fmt.Errorf("error connecting: %v", err)
 	}
@@ -268,3 +244,70 @@ func StartUDPListenerWithHandler(
 
 	return conn, nil
 }
+
+// UnregisterUDPNotification tells the server to stop sending notifications
+// for this token, e.g. on logout
+func UnregisterUDPNotification(serverAddr string, jwt string) error {
+	resp, err := sendRequest(serverAddr, "unregister", jwt, "")
+	if err != nil {
+		return err
+	}
+	if resp.Status != "success" {
+		return fmt.Errorf("unregister failed: %s", resp.Payload)
+	}
+	fmt.Println("✅ UDP notifications unregistered")
+	return nil
+}
+
+// UnsubscribeMangaUDP removes a manga subscription
+func UnsubscribeMangaUDP(serverAddr string, jwt string, mangaID string) error {
+	if mangaID == "" {
+		return fmt.Errorf("manga id required")
+	}
+	resp, err := sendRequest(serverAddr, "unsubscribe", jwt, mangaID)
+	if err != nil {
+		return err
+	}
+	if resp.Status != "success" {
+		return fmt.Errorf("unsubscribe failed: %s", resp.Payload)
+	}
+	return nil
+}
+
+// sendRequest sends one MANGAHUB_REQUEST and waits briefly for the reply
+func sendRequest(serverAddr, action, jwt, payload string) (*UDPResponse, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", serverAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"type":    "MANGAHUB_REQUEST",
+		"action":  action,
+		"token":   jwt,
+		"payload": payload,
+	})
+
+	conn, err := dialServer(udpAddr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write(body); err != nil {
+		return nil, err
+	}
+
+	buffer := make([]byte, 1024)
+	n, err := conn.Read(buffer)
+	if err != nil {
+		return nil, fmt.Errorf("no response to %s: %v", action, err)
+	}
+
+	var resp UDPResponse
+	if err := json.Unmarshal(buffer[:n], &resp); err != nil {
+		return nil, fmt.Errorf("invalid JSON response: %s", string(buffer[:n]))
+	}
+	return &resp, nil
+}
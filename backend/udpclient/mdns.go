@@ -0,0 +1,302 @@
+package udpclient
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// MDNSService is the DNS-SD service type MangaHub servers advertise
+const MDNSService = "_mangahub._tcp.local."
+
+var (
+	mdnsIPv4 = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+	mdnsIPv6 = &net.UDPAddr{IP: net.ParseIP("ff02::fb"), Port: 5353}
+)
+
+// mdnsInstance accumulates the records describing one advertised server
+type mdnsInstance struct {
+	name    string
+	target  string
+	srvPort int
+	txt     map[string]string
+	from    *net.UDPAddr
+	seenAt  time.Time
+}
+
+// DiscoverMDNS browses for _mangahub._tcp services via multicast DNS.
+// TXT records may carry the http, sync, notify and grpc ports; the SRV port
+// is the HTTP port.
+func DiscoverMDNS(timeout time.Duration) ([]DiscoveredServer, error) {
+	query, err := buildMDNSQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := localInterfaceAddrs()
+
+	conn4, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn4.Close()
+
+	conn6, err := net.ListenUDP("udp6", nil)
+	if err != nil {
+		conn6 = nil
+	} else {
+		defer conn6.Close()
+	}
+
+	sentAt := time.Now()
+	deadline := sentAt.Add(timeout)
+	sent := 0
+
+	// Sending from an ephemeral port makes this a legacy one-shot query,
+	// so responders answer us directly by unicast
+	if _, err := conn4.WriteToUDP(query, mdnsIPv4); err == nil {
+		sent++
+	}
+	if conn6 != nil {
+		for _, target := range multicastTargets() {
+			dst := *mdnsIPv6
+			dst.Zone = target.Zone
+			if _, err := conn6.WriteToUDP(query, &dst); err == nil {
+				sent++
+			}
+		}
+	}
+	if sent == 0 {
+		return nil, fmt.Errorf("failed to send mDNS query")
+	}
+
+	fmt.Printf("🔍 Sent mDNS query for %s\n", MDNSService)
+
+	type packet struct {
+		data []byte
+		from *net.UDPAddr
+		at   time.Time
+	}
+	packets := make(chan packet, 16)
+	done := make(chan struct{})
+	defer close(done)
+
+	readers := 0
+	for _, conn := range []*net.UDPConn{conn4, conn6} {
+		if conn == nil {
+			continue
+		}
+		readers++
+		conn.SetReadDeadline(deadline)
+		go func(conn *net.UDPConn) {
+			buffer := make([]byte, 9000)
+			for {
+				n, from, err := conn.ReadFromUDP(buffer)
+				if err != nil {
+					select {
+					case packets <- packet{}:
+					case <-done:
+					}
+					return
+				}
+				data := make([]byte, n)
+				copy(data, buffer[:n])
+				select {
+				case packets <- packet{data: data, from: from, at: time.Now()}:
+				case <-done:
+					return
+				}
+			}
+		}(conn)
+	}
+
+	instances := map[string]*mdnsInstance{}
+	hosts := map[string][]net.IP{}
+
+	for readers > 0 {
+		p := <-packets
+		if p.data == nil {
+			readers--
+			continue
+		}
+		parseMDNSResponse(p.data, p.from, p.at, instances, hosts)
+	}
+
+	var servers []DiscoveredServer
+	seen := map[string]bool{}
+	for _, inst := range instances {
+		server, ok := inst.toServer(hosts, addrs, sentAt)
+		if !ok || seen[server.Addr] {
+			continue
+		}
+		seen[server.Addr] = true
+		servers = append(servers, server)
+		fmt.Printf("✅ Discovered mDNS server: %s (%s) in %dms\n", server.Name, server.Addr, server.LatencyMs)
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no mDNS server discovered")
+	}
+
+	sort.SliceStable(servers, func(i, j int) bool {
+		return servers[i].LatencyMs < servers[j].LatencyMs
+	})
+
+	return servers, nil
+}
+
+func buildMDNSQuery() ([]byte, error) {
+	name, err := dnsmessage.NewName(MDNSService)
+	if err != nil {
+		return nil, err
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(dnsmessage.Question{
+		Name:  name,
+		Type:  dnsmessage.TypePTR,
+		Class: dnsmessage.ClassINET,
+	}); err != nil {
+		return nil, err
+	}
+	return b.Finish()
+}
+
+func parseMDNSResponse(
+	data []byte,
+	from *net.UDPAddr,
+	at time.Time,
+	instances map[string]*mdnsInstance,
+	hosts map[string][]net.IP,
+) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(data); err != nil || !msg.Header.Response {
+		return
+	}
+
+	instance := func(name string) *mdnsInstance {
+		inst, ok := instances[name]
+		if !ok {
+			inst = &mdnsInstance{name: name, txt: map[string]string{}}
+			instances[name] = inst
+		}
+		if inst.from == nil {
+			inst.from = from
+			inst.seenAt = at
+		}
+		return inst
+	}
+
+	records := append(append(msg.Answers, msg.Authorities...), msg.Additionals...)
+	for _, rr := range records {
+		name := strings.ToLower(rr.Header.Name.String())
+
+		switch body := rr.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if name == MDNSService {
+				instance(strings.ToLower(body.PTR.String()))
+			}
+		case *dnsmessage.SRVResource:
+			if strings.HasSuffix(name, "."+MDNSService) {
+				inst := instance(name)
+				inst.target = strings.ToLower(body.Target.String())
+				inst.srvPort = int(body.Port)
+			}
+		case *dnsmessage.TXTResource:
+			if strings.HasSuffix(name, "."+MDNSService) {
+				inst := instance(name)
+				for _, kv := range body.TXT {
+					key, value, _ := strings.Cut(kv, "=")
+					inst.txt[strings.ToLower(key)] = value
+				}
+			}
+		case *dnsmessage.AResource:
+			hosts[name] = append(hosts[name], net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			hosts[name] = append(hosts[name], net.IP(body.AAAA[:]))
+		}
+	}
+}
+
+// toServer turns the collected records into a DiscoveredServer. An instance
+// needs an SRV record or an http port in its TXT record; without an SRV
+// record the address the answer came from is the host.
+func (inst *mdnsInstance) toServer(hosts map[string][]net.IP, addrs []ifaceAddr, sentAt time.Time) (DiscoveredServer, bool) {
+	if inst.target == "" && inst.txt["http"] == "" {
+		return DiscoveredServer{}, false
+	}
+
+	host := inst.host(hosts[inst.target])
+	if host == "" {
+		return DiscoveredServer{}, false
+	}
+
+	name := strings.TrimSuffix(inst.name, "."+MDNSService)
+	if v := inst.txt["name"]; v != "" {
+		name = v
+	}
+
+	server := DiscoveredServer{
+		Name:      name,
+		Host:      host,
+		Port:      txtPort(inst.txt, "notify", discoveryPort()),
+		HTTPPort:  txtPort(inst.txt, "http", inst.srvPort),
+		SyncPort:  txtPort(inst.txt, "sync", 0),
+		GRPCPort:  txtPort(inst.txt, "grpc", 0),
+		LatencyMs: inst.seenAt.Sub(sentAt).Milliseconds(),
+		Source:    "mdns",
+	}
+	server.Addr = net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+
+	if inst.from != nil {
+		iface, localIP := interfaceFor(inst.from, addrs)
+		server.Interface = iface
+		if localIP != nil {
+			server.LocalIP = localIP.String()
+		}
+	}
+
+	return server, true
+}
+
+// host picks the address to reach the instance at, from its A/AAAA records
+// and then the packet source. IPv4 is preferred as it needs no interface;
+// link-local IPv6 is only dialable with the zone the answer arrived on.
+func (inst *mdnsInstance) host(records []net.IP) string {
+	candidates := append([]net.IP{}, records...)
+	if inst.from != nil {
+		candidates = append(candidates, inst.from.IP)
+	}
+
+	var ipv6 string
+	for _, ip := range candidates {
+		switch {
+		case ip.To4() != nil:
+			return ip.String()
+		case ipv6 != "":
+		case ip.IsLinkLocalUnicast():
+			if inst.from != nil && inst.from.Zone != "" {
+				ipv6 = ip.String() + "%" + inst.from.Zone
+			}
+		default:
+			ipv6 = ip.String()
+		}
+	}
+	return ipv6
+}
+
+func txtPort(txt map[string]string, key string, fallback int) int {
+	if port, err := strconv.Atoi(txt[key]); err == nil && port > 0 && port < 65536 {
+		return port
+	}
+	return fallback
+}
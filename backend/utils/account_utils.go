@@ -0,0 +1,351 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Account is one stored login. Its token lives in the credential store under
+// "token:<username>", everything else in AccountDir(username).
+type Account struct {
+	Username   string    `json:"username"`
+	UserID     int64     `json:"user_id,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	DeviceID   string    `json:"device_id"`
+	AddedAt    time.Time `json:"added_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// accountIndex is accounts/accounts.json
+type accountIndex struct {
+	Active   string    `json:"active"`
+	Accounts []Account `json:"accounts"`
+}
+
+var accountsMu sync.Mutex
+
+func accountsDir() string {
+	return filepath.Join(ConfigDir(), "accounts")
+}
+
+func accountIndexPath() string {
+	return filepath.Join(accountsDir(), "accounts.json")
+}
+
+// AccountDir returns the directory holding one account's caches
+func AccountDir(username string) string {
+	return filepath.Join(accountsDir(), safeName(username))
+}
+
+// safeName keeps a username usable as a directory name
+func safeName(username string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, username)
+	if strings.Trim(name, ".") == "" {
+		name = "_" + name
+	}
+	return name
+}
+
+func loadAccountIndex() (*accountIndex, error) {
+	index := &accountIndex{}
+	data, err := os.ReadFile(accountIndexPath())
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func saveAccountIndex(index *accountIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(accountsDir(), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(accountIndexPath(), data, 0600)
+}
+
+// ListAccounts returns the stored accounts, most recently used first
+func ListAccounts() ([]Account, error) {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+
+	index, err := loadAccountIndex()
+	if err != nil {
+		return nil, err
+	}
+	accounts := append([]Account{}, index.Accounts...)
+	sort.SliceStable(accounts, func(i, j int) bool {
+		return accounts[i].LastUsedAt.After(accounts[j].LastUsedAt)
+	})
+	return accounts, nil
+}
+
+// ActiveAccount returns the username whose token and caches are in use, or
+// "" before anyone logged in
+func ActiveAccount() string {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+
+	index, err := loadAccountIndex()
+	if err != nil {
+		return ""
+	}
+	return index.Active
+}
+
+// UseAccount makes username the active account, adding it to the index with
+// a fresh device ID if it is new
+func UseAccount(username string) (*Account, error) {
+	if username == "" {
+		return nil, fmt.Errorf("username required")
+	}
+
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+
+	index, err := loadAccountIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var account *Account
+	for i := range index.Accounts {
+		if index.Accounts[i].Username == username {
+			account = &index.Accounts[i]
+			break
+		}
+	}
+	if account == nil {
+		index.Accounts = append(index.Accounts, Account{
+			Username: username,
+			DeviceID: DeviceID(),
+			AddedAt:  now,
+		})
+		account = &index.Accounts[len(index.Accounts)-1]
+	}
+	account.LastUsedAt = now
+	index.Active = username
+
+	if err := os.MkdirAll(AccountDir(username), 0700); err != nil {
+		return nil, err
+	}
+	if err := saveAccountIndex(index); err != nil {
+		return nil, err
+	}
+
+	result := *account
+	return &result, nil
+}
+
+// UpdateAccount stores profile details learned after login
+func UpdateAccount(username string, userID int64, role string) error {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+
+	index, err := loadAccountIndex()
+	if err != nil {
+		return err
+	}
+	for i := range index.Accounts {
+		if index.Accounts[i].Username == username {
+			index.Accounts[i].UserID = userID
+			index.Accounts[i].Role = role
+			return saveAccountIndex(index)
+		}
+	}
+	return fmt.Errorf("account %q not found", username)
+}
+
+// RemoveAccount forgets an account: its token, caches and index entry
+func RemoveAccount(username string) error {
+	accountsMu.Lock()
+	index, err := loadAccountIndex()
+	if err != nil {
+		accountsMu.Unlock()
+		return err
+	}
+	kept := index.Accounts[:0]
+	for _, a := range index.Accounts {
+		if a.Username != username {
+			kept = append(kept, a)
+		}
+	}
+	index.Accounts = kept
+	if index.Active == username {
+		index.Active = ""
+	}
+	err = saveAccountIndex(index)
+	accountsMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := TokenStore().Delete(accountTokenKey(username)); err != nil {
+		return err
+	}
+	return os.RemoveAll(AccountDir(username))
+}
+
+// RenameAccount moves an account's index entry, token and caches to a new
+// username after it was renamed on the server
+func RenameAccount(oldName, newName string) error {
+	if newName == "" {
+		return fmt.Errorf("username required")
+	}
+
+	accountsMu.Lock()
+	index, err := loadAccountIndex()
+	if err != nil {
+		accountsMu.Unlock()
+		return err
+	}
+	for i := range index.Accounts {
+		if index.Accounts[i].Username == oldName {
+			index.Accounts[i].Username = newName
+		}
+	}
+	if index.Active == oldName {
+		index.Active = newName
+	}
+	err = saveAccountIndex(index)
+	accountsMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	store := TokenStore()
+	if token, err := store.Get(accountTokenKey(oldName)); err == nil {
+		if err := store.Set(accountTokenKey(newName), token); err != nil {
+			return err
+		}
+		store.Delete(accountTokenKey(oldName))
+	}
+
+	if _, err := os.Stat(AccountDir(oldName)); err == nil {
+		os.RemoveAll(AccountDir(newName))
+		return os.Rename(AccountDir(oldName), AccountDir(newName))
+	}
+	return nil
+}
+
+// ClearAccountData deletes an account's caches, keeping its index entry and
+// device ID so the next login is recognised as the same device. Files named
+// in keep hold data not yet on the server and survive.
+func ClearAccountData(username string, keep ...string) error {
+	dir := AccountDir(username)
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, f := range files {
+		if slices.Contains(keep, f.Name()) {
+			kept++
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, f.Name())); err != nil {
+			return err
+		}
+	}
+	if kept == 0 {
+		return os.Remove(dir)
+	}
+	return nil
+}
+
+// ClearActiveAccount leaves no account active, as after logout
+func ClearActiveAccount() error {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+
+	index, err := loadAccountIndex()
+	if err != nil {
+		return err
+	}
+	index.Active = ""
+	return saveAccountIndex(index)
+}
+
+// HasAccountToken reports whether a token is stored for username
+func HasAccountToken(username string) bool {
+	token, err := TokenStore().Get(accountTokenKey(username))
+	return err == nil && token != ""
+}
+
+// AccountDeviceID returns the persistent device ID of the active account
+func AccountDeviceID() string {
+	accountsMu.Lock()
+	defer accountsMu.Unlock()
+
+	index, err := loadAccountIndex()
+	if err == nil {
+		for _, a := range index.Accounts {
+			if a.Username == index.Active && a.DeviceID != "" {
+				return a.DeviceID
+			}
+		}
+	}
+	return DeviceID()
+}
+
+// SaveAccountJSON writes v to name inside the active account's directory
+func SaveAccountJSON(name string, v interface{}) error {
+	active := ActiveAccount()
+	if active == "" {
+		return fmt.Errorf("no active account")
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := AccountDir(active)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, name), data, 0600)
+}
+
+// LoadAccountJSON reads name from the active account's directory into v
+func LoadAccountJSON(name string, v interface{}) error {
+	active := ActiveAccount()
+	if active == "" {
+		return fmt.Errorf("no active account")
+	}
+
+	data, err := os.ReadFile(filepath.Join(AccountDir(active), name))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func accountTokenKey(username string) string {
+	return tokenKey + ":" + username
+}
@@ -1,42 +1,122 @@
 package utils
 
 import (
+	"encoding/json"
+	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
-func configDir() string {
+// ConfigDir returns the per-user directory holding the app state
+func ConfigDir() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		home = "."
 	}
 	return filepath.Join(home, ".mangahub-desktop")
 }
+
+// SelectedServer is the MangaHub server the user picked (or discovery chose)
+type SelectedServer struct {
+	Name       string    `json:"name"`
+	Host       string    `json:"host"`
+	Port       int       `json:"port"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Interface  string    `json:"interface,omitempty"`
+	LocalIP    string    `json:"local_ip,omitempty"`
+	HTTPPort   int       `json:"http_port,omitempty"`
+	SyncPort   int       `json:"sync_port,omitempty"`
+	GRPCPort   int       `json:"grpc_port,omitempty"`
+	SelectedAt time.Time `json:"selected_at"`
+}
+
+// Addr returns the UDP host:port of the selected server
+func (s *SelectedServer) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+}
+
+func selectedServerPath() string {
+	return filepath.Join(ConfigDir(), "server.json")
+}
+
+// SaveSelectedServer persists the chosen server so it survives restarts
+func SaveSelectedServer(server SelectedServer) error {
+	if server.SelectedAt.IsZero() {
+		server.SelectedAt = time.Now()
+	}
+
+	data, err := json.MarshalIndent(server, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	os.MkdirAll(ConfigDir(), 0700)
+	return os.WriteFile(selectedServerPath(), data, 0600)
+}
+
+// LoadSelectedServer returns the persisted server, migrating the legacy
+// udp_server file if no selection has been saved yet
+func LoadSelectedServer() (*SelectedServer, error) {
+	data, err := os.ReadFile(selectedServerPath())
+	if err == nil {
+		var server SelectedServer
+		if err := json.Unmarshal(data, &server); err != nil {
+			return nil, err
+		}
+		return &server, nil
+	}
+
+	legacy, legacyErr := os.ReadFile(filepath.Join(ConfigDir(), "udp_server"))
+	if legacyErr != nil {
+		return nil, err
+	}
+
+	host, portStr, splitErr := net.SplitHostPort(strings.TrimSpace(string(legacy)))
+	if splitErr != nil {
+		return nil, splitErr
+	}
+	port, _ := strconv.Atoi(portStr)
+
+	return &SelectedServer{Host: host, Port: port}, nil
+}
+
+// ClearSelectedServer forgets the persisted server selection
+func ClearSelectedServer() error {
+	os.Remove(filepath.Join(ConfigDir(), "udp_server"))
+	if err := os.Remove(selectedServerPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 func SaveUDPServerAddr(addr string) error {
-	return os.WriteFile(
-		filepath.Join(configDir(), "udp_server"),
-		[]byte(addr),
-		0600,
-	)
+	host, portStr, err := net.SplitHostPort(strings.TrimSpace(addr))
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+
+	return SaveSelectedServer(SelectedServer{Host: host, Port: port})
 }
+
 func LoadServerIPAddr() (string, error) {
-	data, err := os.ReadFile(
-		filepath.Join(configDir(), "udp_server"),
-	)
+	server, err := LoadSelectedServer()
 	if err != nil {
 		return "", err
 	}
-	addr := strings.Split(string(data), ":")[0]
-	return strings.TrimSpace(addr), nil
+	return server.Host, nil
 }
 
 func LoadUDPServerAddr() (string, error) {
-	data, err := os.ReadFile(
-		filepath.Join(configDir(), "udp_server"),
-	)
+	server, err := LoadSelectedServer()
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(data)), nil
+	return server.Addr(), nil
 }
@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"mangahub-desktop/backend/credstore"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
@@ -63,6 +66,63 @@ func ValidateToken(tokenString string) (*SignedDetails, error) {
 	return claims, nil
 }
 
+// ParseTokenUnverified decodes the claims without checking the signature.
+// The client does not hold the signing secret, so the result is only good
+// for reading the expiry and display fields.
+func ParseTokenUnverified(tokenString string) (*SignedDetails, error) {
+	claims := &SignedDetails{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+const tokenKey = "token"
+
+var (
+	tokenStore     credstore.Store
+	tokenStoreOnce sync.Once
+	tokenStoreMu   sync.Mutex
+)
+
+// SetTokenStore replaces the credential store holding the token, e.g. with
+// credstore.NewMemory() to run without a desktop session
+func SetTokenStore(store credstore.Store) {
+	tokenStoreOnce.Do(func() {})
+	tokenStoreMu.Lock()
+	defer tokenStoreMu.Unlock()
+	tokenStore = store
+}
+
+// TokenStore returns the credential store, opening it on first use and
+// migrating a plaintext token file left by older versions
+func TokenStore() credstore.Store {
+	tokenStoreOnce.Do(func() {
+		store, err := credstore.Open(ConfigDir())
+		if err != nil {
+			// Keep the session in memory rather than writing it in plaintext
+			LogError(fmt.Sprintf("🔐 Credential store unavailable, token will not be persisted: %v", err))
+			store = credstore.NewMemory()
+		}
+		if path, err := tokenFilePath(); err == nil {
+			if err := credstore.MigrateFile(store, tokenKey, path); err != nil {
+				LogError(fmt.Sprintf("🔐 Failed to migrate plaintext token: %v", err))
+			}
+		}
+		migrateLegacyToken(store)
+		LogInfo("🔐 Using credential store: " + store.Name())
+
+		tokenStoreMu.Lock()
+		tokenStore = store
+		tokenStoreMu.Unlock()
+	})
+
+	tokenStoreMu.Lock()
+	defer tokenStoreMu.Unlock()
+	return tokenStore
+}
+
+// tokenFilePath is where versions before the credential store kept the token
 func tokenFilePath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -71,38 +131,44 @@ func tokenFilePath() (string, error) {
 	return filepath.Join(home, ".mangahub-desktop", "token"), nil
 }
 
-func SaveToken(token string) error {
-	path, err := tokenFilePath()
-	if err != nil {
-		return err
+// migrateLegacyToken files a token saved before accounts existed under the
+// account it belongs to
+func migrateLegacyToken(store credstore.Store) {
+	token, err := store.Get(tokenKey)
+	if err != nil || ActiveAccount() != "" {
+		return
+	}
+	claims, err := ParseTokenUnverified(token)
+	if err != nil || claims.Username == "" {
+		return
+	}
+	if _, err := UseAccount(claims.Username); err != nil {
+		LogError(fmt.Sprintf("🔐 Failed to create account for stored token: %v", err))
+		return
+	}
+	if err := store.Set(accountTokenKey(claims.Username), token); err == nil {
+		store.Delete(tokenKey)
 	}
-
-	// ensure folder exists
-	os.MkdirAll(filepath.Dir(path), 0700)
-
-	return os.WriteFile(path, []byte(token), 0600)
 }
 
-func LoadToken() (string, error) {
-	path, err := tokenFilePath()
-	if err != nil {
-		return "", err
+// activeTokenKey is the credential store key of the active account's token
+func activeTokenKey() string {
+	if active := ActiveAccount(); active != "" {
+		return accountTokenKey(active)
 	}
+	return tokenKey
+}
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return "", err
-	}
+func SaveToken(token string) error {
+	return TokenStore().Set(activeTokenKey(), token)
+}
 
-	return string(data), nil
+func LoadToken() (string, error) {
+	return TokenStore().Get(activeTokenKey())
 }
 
 func ClearToken() error {
-	path, err := tokenFilePath()
-	if err != nil {
-		return err
-	}
-	return os.Remove(path)
+	return TokenStore().Delete(activeTokenKey())
 }
 
 func DeviceID() string {
@@ -0,0 +1,229 @@
+// Package validation checks credentials on the client before they are sent,
+// returning per-field problems the Login page can show next to each input.
+package validation
+
+import (
+	_ "embed"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 32
+	MinPasswordLength = 8
+	// MaxPasswordLength is bcrypt's input limit in bytes
+	MaxPasswordLength = 72
+	// MinPasswordEntropy is the estimated strength, in bits, a password needs
+	MinPasswordEntropy = 36
+)
+
+// Fields problems refer to
+const (
+	FieldUsername = "username"
+	FieldPassword = "password"
+)
+
+// Problem is one reason a field was rejected
+type Problem struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Result is the outcome of validating a signup form
+type Result struct {
+	Valid    bool      `json:"valid"`
+	Problems []Problem `json:"problems"`
+	// Entropy is the estimated password strength in bits
+	Entropy  float64 `json:"entropy"`
+	Strength string  `json:"strength"` // very_weak | weak | reasonable | strong | very_strong
+}
+
+// Error carries validation problems to the frontend as a structured object
+type Error struct {
+	Code     string    `json:"code"`
+	Message  string    `json:"message"`
+	Problems []Problem `json:"problems"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Structured lets httpclient.ErrorFormatter pass the error on as an object
+func (e *Error) Structured() any {
+	return e
+}
+
+// Err returns the problems as an *Error, or nil when the result is valid
+func (r Result) Err() error {
+	if r.Valid {
+		return nil
+	}
+	return &Error{
+		Code:     "validation_failed",
+		Message:  r.Problems[0].Message,
+		Problems: r.Problems,
+	}
+}
+
+//go:embed common_passwords.txt
+var commonPasswordList string
+
+var commonPasswords = func() map[string]bool {
+	set := map[string]bool{}
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			set[line] = true
+		}
+	}
+	return set
+}()
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidateSignup checks a username and password pair
+func ValidateSignup(username, password string) Result {
+	problems := ValidateUsername(username)
+	problems = append(problems, ValidatePassword(password, username)...)
+
+	entropy := EstimateEntropy(password)
+	if isCommon(strings.ToLower(password)) {
+		// Guessed from a list, however varied it looks
+		entropy = 0
+	}
+	return Result{
+		Valid:    len(problems) == 0,
+		Problems: problems,
+		Entropy:  math.Round(entropy*10) / 10,
+		Strength: Strength(entropy),
+	}
+}
+
+// ValidateUsername checks length and allowed characters
+func ValidateUsername(username string) []Problem {
+	var problems []Problem
+	n := len([]rune(username))
+
+	switch {
+	case n < MinUsernameLength:
+		problems = append(problems, Problem{FieldUsername, "too_short", "Username must be at least 3 characters"})
+	case n > MaxUsernameLength:
+		problems = append(problems, Problem{FieldUsername, "too_long", "Username must be at most 32 characters"})
+	}
+	if n > 0 && !usernamePattern.MatchString(username) {
+		problems = append(problems, Problem{FieldUsername, "invalid_characters",
+			"Username may only contain letters, digits, '.', '_' and '-', and must start with a letter or digit"})
+	}
+	return problems
+}
+
+// ValidatePassword checks length, strength and the common-password list.
+// username may be empty.
+func ValidatePassword(password, username string) []Problem {
+	var problems []Problem
+
+	if len([]rune(password)) < MinPasswordLength {
+		problems = append(problems, Problem{FieldPassword, "too_short", "Password must be at least 8 characters"})
+	}
+	if len(password) > MaxPasswordLength {
+		problems = append(problems, Problem{FieldPassword, "too_long", "Password must be at most 72 bytes"})
+	}
+	if password == "" {
+		return problems
+	}
+
+	lower := strings.ToLower(password)
+	switch {
+	case isCommon(lower):
+		problems = append(problems, Problem{FieldPassword, "common_password", "This password is too common, choose something less predictable"})
+	case len(username) >= MinUsernameLength && strings.Contains(lower, strings.ToLower(username)):
+		problems = append(problems, Problem{FieldPassword, "contains_username", "Password must not contain your username"})
+	case EstimateEntropy(password) < MinPasswordEntropy:
+		problems = append(problems, Problem{FieldPassword, "weak_password", "Password is too easy to guess, make it longer or mix in other kinds of characters"})
+	}
+	return problems
+}
+
+// isCommon also catches list entries decorated with trailing digits or
+// symbols, like "dragon2024!"
+func isCommon(lower string) bool {
+	if commonPasswords[lower] {
+		return true
+	}
+	stem := strings.TrimRightFunc(lower, func(r rune) bool {
+		return unicode.IsDigit(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	})
+	return len(stem) >= 4 && commonPasswords[stem]
+}
+
+// EstimateEntropy approximates the password's strength in bits from the
+// character classes used. Characters repeating or continuing a run of the
+// previous one ("aaa", "abc", "321") count for half.
+func EstimateEntropy(password string) float64 {
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	effective := 0.0
+	var prev rune = -1
+
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r < 128:
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+
+		if prev >= 0 && (r == prev || r == prev+1 || r == prev-1) {
+			effective += 0.5
+		} else {
+			effective++
+		}
+		prev = r
+	}
+
+	pool := 0
+	if hasLower {
+		pool += 26
+	}
+	if hasUpper {
+		pool += 26
+	}
+	if hasDigit {
+		pool += 10
+	}
+	if hasSymbol {
+		pool += 33
+	}
+	if hasOther {
+		pool += 100
+	}
+	if pool == 0 {
+		return 0
+	}
+	return effective * math.Log2(float64(pool))
+}
+
+// Strength buckets an entropy estimate for display
+func Strength(entropy float64) string {
+	switch {
+	case entropy < 28:
+		return "very_weak"
+	case entropy < MinPasswordEntropy:
+		return "weak"
+	case entropy < 60:
+		return "reasonable"
+	case entropy < 80:
+		return "strong"
+	}
+	return "very_strong"
+}
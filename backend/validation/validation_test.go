@@ -0,0 +1,143 @@
+package validation
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// codes lists the problem codes reported for field
+func codes(problems []Problem, field string) []string {
+	var out []string
+	for _, p := range problems {
+		if p.Field == field {
+			out = append(out, p.Code)
+		}
+	}
+	return out
+}
+
+func sameCodes(got, want []string) bool {
+	return strings.Join(got, ",") == strings.Join(want, ",")
+}
+
+func TestValidateUsername(t *testing.T) {
+	tests := []struct {
+		username string
+		want     []string
+	}{
+		{"reader", nil},
+		{"manga_fan.42", nil},
+		{"ab", []string{"too_short"}},
+		{"", []string{"too_short"}},
+		{strings.Repeat("a", MaxUsernameLength), nil},
+		{strings.Repeat("a", MaxUsernameLength+1), []string{"too_long"}},
+		{"_leading", []string{"invalid_characters"}},
+		{"with space", []string{"invalid_characters"}},
+		{"読者さん", []string{"invalid_characters"}},
+	}
+	for _, tt := range tests {
+		if got := codes(ValidateUsername(tt.username), FieldUsername); !sameCodes(got, tt.want) {
+			t.Errorf("ValidateUsername(%q) = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		username string
+		want     []string
+	}{
+		{"strong", "Vq8#mZ2!rT", "reader", nil},
+		{"empty", "", "reader", []string{"too_short"}},
+		{"short", "Vq8#mZ2", "reader", []string{"too_short"}},
+		{"common", "password", "reader", []string{"common_password"}},
+		{"common stem with suffix", "dragon2024!", "reader", []string{"common_password"}},
+		{"common stem, any case", "Monkey123", "reader", []string{"common_password"}},
+		{"stem too short to count", "abc12345!Xy", "reader", nil},
+		{"contains username", "xReAdEr#2024q", "reader", []string{"contains_username"}},
+		{"short username ignored", "xy#Vq8mZ2!rT", "xy", nil},
+		{"predictable", "aaaaaaaaaa", "reader", []string{"weak_password"}},
+	}
+	for _, tt := range tests {
+		if got := codes(ValidatePassword(tt.password, tt.username), FieldPassword); !sameCodes(got, tt.want) {
+			t.Errorf("%s: ValidatePassword(%q, %q) = %v, want %v", tt.name, tt.password, tt.username, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePasswordByteLimit(t *testing.T) {
+	// 24 three-byte runes are 72 bytes: the limit is bcrypt's, in bytes
+	atLimit := strings.Repeat("語", 24)
+	overLimit := atLimit + "a"
+	if len(atLimit) != MaxPasswordLength {
+		t.Fatalf("test password is %d bytes", len(atLimit))
+	}
+
+	if got := codes(ValidatePassword(atLimit, ""), FieldPassword); len(got) > 0 && got[0] == "too_long" {
+		t.Errorf("72 bytes rejected as too long: %v", got)
+	}
+	if got := codes(ValidatePassword(overLimit, ""), FieldPassword); len(got) == 0 || got[0] != "too_long" {
+		t.Errorf("73 bytes in 25 characters accepted: %v", got)
+	}
+	// Few characters but many bytes is still too short
+	if got := codes(ValidatePassword("語語語", ""), FieldPassword); len(got) == 0 || got[0] != "too_short" {
+		t.Errorf("3 characters in 9 bytes accepted: %v", got)
+	}
+}
+
+func TestEstimateEntropy(t *testing.T) {
+	tests := []struct {
+		password string
+		want     float64
+	}{
+		{"", 0},
+		{"a", math.Log2(26)},
+		{"ab", 1.5 * math.Log2(26)},
+		{"aaaa", 2.5 * math.Log2(26)},
+		{"aZ", 2 * math.Log2(52)},
+		{"a1", 2 * math.Log2(36)},
+		{"a!", 2 * math.Log2(59)},
+		{"é", math.Log2(100)},
+	}
+	for _, tt := range tests {
+		if got := EstimateEntropy(tt.password); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("EstimateEntropy(%q) = %.3f, want %.3f", tt.password, got, tt.want)
+		}
+	}
+
+	if EstimateEntropy("abcdefgh") >= EstimateEntropy("qzjxkvwm") {
+		t.Error("a run of letters should count for less than scattered ones")
+	}
+}
+
+func TestStrength(t *testing.T) {
+	tests := []struct {
+		entropy float64
+		want    string
+	}{
+		{0, "very_weak"},
+		{27.9, "very_weak"},
+		{28, "weak"},
+		{MinPasswordEntropy - 0.1, "weak"},
+		{MinPasswordEntropy, "reasonable"},
+		{59.9, "reasonable"},
+		{60, "strong"},
+		{79.9, "strong"},
+		{80, "very_strong"},
+	}
+	for _, tt := range tests {
+		if got := Strength(tt.entropy); got != tt.want {
+			t.Errorf("Strength(%v) = %q, want %q", tt.entropy, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSignupCommonHasNoEntropy(t *testing.T) {
+	result := ValidateSignup("reader", "dragon2024!")
+	if result.Valid || result.Entropy != 0 || result.Strength != "very_weak" {
+		t.Errorf("ValidateSignup = %+v", result)
+	}
+}
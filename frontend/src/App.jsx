@@ -1,11 +1,12 @@
 import { useEffect, useState } from "react";
 import LoginPage from "./pages/Login/Login";
 import LibraryPage from "./pages/Library/page";
+import ImportPage from "./pages/Import/page";
 import { EventsOn } from "../wailsjs/runtime/runtime";
 import { showToast } from "./utils/toast";
 import Navbar from "./components/Navbar";
-import { Stop as StopNotify } from "../wailsjs/go/services/NotifyService";
-import { Stop as StopSync } from "../wailsjs/go/services/SyncService";
+import { GetStartupState } from "../wailsjs/go/main/App";
+import { GetQueueState, ResolveConflict } from "../wailsjs/go/services/LibraryService";
 
 import HomePage from "./pages/Home/page";
 import MangaDetailPage from "./pages/MangaDetail/page";
@@ -16,6 +17,8 @@ import "./App.css";
 
 function App() {
   const [loggedIn, setLoggedIn] = useState(false);
+  // Session restore progress from the backend; login is shown once it gives up
+  const [startup, setStartup] = useState({ phase: "checking" });
   const [selectedMangaId, setSelectedMangaId] = useState(null);
   const [tab, setTab] = useState("home");
   const [backgroundMode, setBackgroundMode] = useState("default");
@@ -35,21 +38,100 @@ function App() {
     setTab("chat");
   };
 
-  const handleLogout = async () => {
-    // Stop UDP and TCP connections
-    try {
-      await StopNotify();
-      await StopSync();
-      console.log("UDP and TCP connections closed");
-    } catch (err) {
-      console.error("Error closing connections:", err);
-    }
-    
+  // Drops everything tied to the logged-in user and shows the login screen
+  const clearUserState = () => {
+    Object.keys(localStorage)
+      .filter((key) => key.startsWith("manga_sub_"))
+      .forEach((key) => localStorage.removeItem(key));
+
+    setSyncBroadcasts([]);
+    setSelectedMangaId(null);
+    setChatMangaId(null);
+    setChatMangaName("");
+    setStartup({ phase: "login_required" });
     setLoggedIn(false);
     setTab("home");
+  };
+
+  const handleLogout = async () => {
+    // The backend Logout already stopped UDP, TCP sync and chat
+    clearUserState();
     showToast("👋 Logged out successfully");
   };
 
+  useEffect(() => {
+    if (!loggedIn) return;
+
+    // A queued library change the server disagrees with holds up the rest of
+    // the queue until answered, whichever page is open
+    let asking = null;
+    const askConflict = (m) => {
+      if (!m?.conflict || asking === m.id) return;
+      asking = m.id;
+      const c = m.conflict;
+      const question =
+        m.kind === "progress"
+          ? `${c.manga_id}: you read up to chapter ${c.local_chapter} offline, but another device is at chapter ${c.server_chapter}.\n\nKeep chapter ${c.local_chapter}?`
+          : `${c.manga_id}: ${c.reason}.\n\nApply your offline change (${m.kind}) anyway?`;
+      const resolution = confirm(question) ? "keep_local" : "keep_server";
+      ResolveConflict(m.id, resolution)
+        .catch((err) => showToast(`❌ ${err?.message || "Failed to resolve conflict"}`))
+        .finally(() => {
+          asking = null;
+        });
+    };
+
+    // A conflict may have been parked before this listener existed
+    GetQueueState()
+      .then((q) => askConflict(q.mutations?.[0]))
+      .catch(() => {});
+    const offConflict = EventsOn("library:conflict", askConflict);
+
+    return () => offConflict();
+  }, [loggedIn]);
+
+  useEffect(() => {
+    // The backend paused sync, chat and notifications; the token is gone or
+    // rejected, so the user has to log in again
+    const offExpired = EventsOn("auth:expired", () => {
+      clearUserState();
+      showToast("🔒 Your session expired - please log in again", { duration: 8000 });
+    });
+
+    // Renewal failed but the token still works for a while
+    const offExpiring = EventsOn("auth:expiring", (event) => {
+      const minutes = Math.max(1, Math.round((new Date(event.expires_at) - Date.now()) / 60000));
+      showToast(`⚠️ Could not renew your session - it ends in about ${minutes} min`, {
+        duration: 8000,
+      });
+    });
+
+    return () => {
+      offExpired();
+      offExpiring();
+    };
+  }, []);
+
+  useEffect(() => {
+    const applyStartup = (state) => {
+      setStartup(state);
+      if (state.phase === "ready") {
+        setLoggedIn(true);
+        if (state.offline) {
+          showToast("⚠️ Server unreachable - working offline");
+        }
+      }
+    };
+
+    // The restore may have finished before this component mounted
+    GetStartupState().then(applyStartup).catch(() =>
+      setStartup({ phase: "login_required" })
+    );
+    const offStartup = EventsOn("startup:progress", applyStartup);
+
+    return () => offStartup();
+  }, []);
+
   useEffect(() => {
     // NotifyService will be started automatically after login by backend
     // No need to call Start() here anymore
@@ -100,6 +182,23 @@ function App() {
     };
   }, []);
 
+  const restoring = ["checking", "restoring", "verifying"].includes(startup.phase);
+
+  if (!loggedIn && restoring) {
+    return (
+      <div className="app-root">
+        <div className="app-background" data-mode={backgroundMode} />
+        <div className="app-content">
+          <p>
+            {startup.phase === "checking"
+              ? "Starting..."
+              : `Restoring session${startup.username ? ` for ${startup.username}` : ""}...`}
+          </p>
+        </div>
+      </div>
+    );
+  }
+
   if (!loggedIn) {
     return (
       <div className="app-root">
@@ -138,6 +237,8 @@ function App() {
           />
         )}
         
+        {tab === "import" && <ImportPage />}
+
         {tab === "chat" && (
           <ChatPage
             initialMangaId={chatMangaId}
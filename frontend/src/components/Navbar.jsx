@@ -56,6 +56,17 @@ export default function Navbar({ current, onChange, onLogout }) {
           <span style={styles.btnText}>Library</span>
         </button>
 
+        <button
+          onClick={() => onChange("import")}
+          style={
+            current === "import"
+              ? { ...styles.btn, ...styles.active }
+              : styles.btn
+          }
+        >
+          <span style={styles.btnText}>Import / Export</span>
+        </button>
+
         <button
           onClick={() => onChange("chat")}
           style={
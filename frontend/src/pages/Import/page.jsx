@@ -0,0 +1,378 @@
+import { useEffect, useState } from "react";
+import {
+  ChooseMALExport,
+  ChooseTachiyomiBackup,
+  ApplyImport,
+  DiscardImport,
+} from "../../../wailsjs/go/services/ImportService";
+import { ExportLibrary } from "../../../wailsjs/go/services/ExportService";
+import { EventsOn } from "../../../wailsjs/runtime/runtime";
+import { showToast } from "../../utils/toast";
+
+const STATUSES = [
+  { value: "reading", label: "Reading" },
+  { value: "completed", label: "Completed" },
+  { value: "plan_to_read", label: "Plan to Read" },
+];
+
+const STATE_LABELS = {
+  matched: "✅ Matched",
+  ambiguous: "❓ Check match",
+  unmatched: "❌ No match",
+  skipped: "⏭️ Skipped",
+};
+
+const EXPORT_FORMATS = [
+  { format: "json", label: "JSON backup", hint: "Everything, including reading history" },
+  { format: "csv", label: "CSV spreadsheet", hint: "Title, author, status, chapter, volume, notes, last updated" },
+  { format: "mal", label: "MyAnimeList XML", hint: "For MAL and trackers that import MAL lists" },
+];
+
+const RESULT_ICONS = {
+  imported: "✅",
+  queued: "📥",
+  skipped: "⏭️",
+  failed: "❌",
+};
+
+export default function ImportPage() {
+  const [preview, setPreview] = useState(null);
+  // index -> { manga_id, status } chosen in the review
+  const [choices, setChoices] = useState({});
+  const [progress, setProgress] = useState(null);
+  const [busy, setBusy] = useState(false);
+  const [report, setReport] = useState(null);
+
+  useEffect(() => {
+    const offProgress = EventsOn("import:progress", setProgress);
+    return () => offProgress();
+  }, []);
+
+  const run = async (choose) => {
+    setBusy(true);
+    setReport(null);
+    setProgress(null);
+    try {
+      const result = await choose();
+      if (!result) return; // dialog cancelled
+      const initial = {};
+      result.items.forEach((item) => {
+        initial[item.index] = { manga_id: item.manga_id || "", status: item.status };
+      });
+      setChoices(initial);
+      setPreview(result);
+    } catch (err) {
+      showToast(`❌ ${err?.message || "Import failed"}`);
+    } finally {
+      setBusy(false);
+      setProgress(null);
+    }
+  };
+
+  const choose = (index, field, value) =>
+    setChoices((prev) => ({ ...prev, [index]: { ...prev[index], [field]: value } }));
+
+  const apply = async () => {
+    const decisions = preview.items.map((item) => ({
+      index: item.index,
+      manga_id: choices[item.index].manga_id,
+      status: choices[item.index].status,
+    }));
+    setBusy(true);
+    try {
+      setReport(await ApplyImport(preview.id, decisions));
+      setPreview(null);
+      showToast("✅ Import finished");
+    } catch (err) {
+      showToast(`❌ ${err?.message || "Import failed"}`);
+    } finally {
+      setBusy(false);
+      setProgress(null);
+    }
+  };
+
+  const exportLibrary = async (format) => {
+    setBusy(true);
+    try {
+      const path = await ExportLibrary(format);
+      if (path) showToast(`✅ Library exported to ${path}`);
+    } catch (err) {
+      showToast(`❌ ${err?.message || "Export failed"}`);
+    } finally {
+      setBusy(false);
+    }
+  };
+
+  const discard = async () => {
+    await DiscardImport(preview.id);
+    setPreview(null);
+  };
+
+  const selected = preview
+    ? preview.items.filter((i) => choices[i.index].manga_id && choices[i.index].status).length
+    : 0;
+
+  return (
+    <div style={styles.container}>
+      <h1 style={styles.title}>📥 Import / 📤 Export</h1>
+
+      {!preview && (
+        <div style={styles.card}>
+          <p style={styles.text}>
+            Export your list from MyAnimeList (Profile → Export → Manga List)
+            and pick the .xml or .xml.gz file. Every title is matched against
+            the MangaHub catalog and shown for review before anything is added.
+          </p>
+          <button style={styles.button} disabled={busy} onClick={() => run(ChooseMALExport)}>
+            Import MyAnimeList export
+          </button>
+
+          <p style={styles.text}>
+            From Tachiyomi or Mihon, create a backup (Settings → Backup and
+            restore) and pick the .tachibk file. The last chapter marked read
+            in each series becomes its progress.
+          </p>
+          <button style={styles.button} disabled={busy} onClick={() => run(ChooseTachiyomiBackup)}>
+            Import Tachiyomi/Mihon backup
+          </button>
+        </div>
+      )}
+
+      {!preview && (
+        <div style={styles.card}>
+          <p style={styles.text}>Save your library to a file.</p>
+          <div style={styles.exports}>
+            {EXPORT_FORMATS.map((f) => (
+              <button
+                key={f.format}
+                style={styles.button}
+                title={f.hint}
+                disabled={busy}
+                onClick={() => exportLibrary(f.format)}
+              >
+                {f.label}
+              </button>
+            ))}
+          </div>
+        </div>
+      )}
+
+      {progress && (
+        <div style={styles.progress}>
+          {progress.stage === "matching" ? "Matching titles" : "Adding to library"}…{" "}
+          {progress.done}/{progress.total}
+        </div>
+      )}
+
+      {preview && (
+        <div style={styles.card}>
+          <div style={styles.summary}>
+            {preview.matched} matched · {preview.ambiguous} to check ·{" "}
+            {preview.unmatched} without match · {preview.skipped} skipped
+          </div>
+
+          <table style={styles.table}>
+            <thead>
+              <tr>
+                <th style={styles.th}>Title</th>
+                <th style={styles.th}>Progress</th>
+                <th style={styles.th}>State</th>
+                <th style={styles.th}>MangaHub manga</th>
+                <th style={styles.th}>List</th>
+              </tr>
+            </thead>
+            <tbody>
+              {preview.items.map((item) => (
+                <tr key={item.index}>
+                  <td style={styles.td}>
+                    {item.title}
+                    <div style={styles.muted}>{item.source_status}</div>
+                  </td>
+                  <td style={styles.td}>
+                    {item.chapter > 0 ? `Ch. ${item.chapter}` : "—"}
+                  </td>
+                  <td style={styles.td} title={item.reason}>
+                    {STATE_LABELS[item.state]}
+                  </td>
+                  <td style={styles.td}>
+                    <select
+                      style={styles.select}
+                      value={choices[item.index].manga_id}
+                      onChange={(e) => choose(item.index, "manga_id", e.target.value)}
+                    >
+                      <option value="">Don't import</option>
+                      {item.candidates.map((c) => (
+                        <option key={c.manga.id} value={c.manga.id}>
+                          {c.manga.title} ({Math.round(c.score * 100)}%)
+                        </option>
+                      ))}
+                    </select>
+                  </td>
+                  <td style={styles.td}>
+                    <select
+                      style={styles.select}
+                      value={choices[item.index].status}
+                      onChange={(e) => choose(item.index, "status", e.target.value)}
+                    >
+                      <option value="">None</option>
+                      {STATUSES.map((s) => (
+                        <option key={s.value} value={s.value}>
+                          {s.label}
+                        </option>
+                      ))}
+                    </select>
+                  </td>
+                </tr>
+              ))}
+            </tbody>
+          </table>
+
+          <div style={styles.actions}>
+            <button style={styles.secondary} disabled={busy} onClick={discard}>
+              Cancel
+            </button>
+            <button style={styles.button} disabled={busy || selected === 0} onClick={apply}>
+              Import {selected} title(s)
+            </button>
+          </div>
+        </div>
+      )}
+
+      {report && (
+        <div style={styles.card}>
+          <div style={styles.summary}>
+            {report.imported} imported · {report.queued} queued until online ·{" "}
+            {report.skipped} skipped · {report.failed} failed
+          </div>
+          <ul style={styles.list}>
+            {report.results.map((r) => (
+              <li key={r.index}>
+                {RESULT_ICONS[r.result]} {r.title}
+                {r.result === "failed" && `: ${r.error}`}
+                {r.result === "skipped" && r.reason && ` (${r.reason})`}
+              </li>
+            ))}
+          </ul>
+        </div>
+      )}
+    </div>
+  );
+}
+
+const styles = {
+  container: {
+    padding: "32px 24px",
+    minHeight: "100vh",
+    maxWidth: 1000,
+    margin: "0 auto",
+  },
+
+  title: {
+    textAlign: "center",
+    fontSize: 36,
+    fontWeight: 800,
+    color: "#ff6b9d",
+  },
+
+  card: {
+    marginTop: 24,
+    padding: 24,
+    background: "rgba(255, 255, 255, 0.85)",
+    backdropFilter: "blur(12px)",
+    borderRadius: 20,
+    border: "2px solid rgba(255, 182, 185, 0.5)",
+    boxShadow: "0 8px 32px rgba(255, 182, 185, 0.3)",
+  },
+
+  text: {
+    color: "#555",
+    lineHeight: 1.5,
+  },
+
+  summary: {
+    color: "#ff6b9d",
+    fontWeight: 600,
+    marginBottom: 16,
+  },
+
+  progress: {
+    marginTop: 16,
+    textAlign: "center",
+    color: "#ff8ba7",
+    fontWeight: 500,
+  },
+
+  table: {
+    width: "100%",
+    borderCollapse: "collapse",
+    fontSize: 14,
+  },
+
+  th: {
+    textAlign: "left",
+    padding: 8,
+    color: "#ff8ba7",
+    borderBottom: "2px solid rgba(255, 182, 185, 0.5)",
+  },
+
+  td: {
+    padding: 8,
+    borderBottom: "1px solid rgba(255, 182, 185, 0.3)",
+    color: "#444",
+    verticalAlign: "top",
+  },
+
+  muted: {
+    fontSize: 12,
+    color: "#999",
+  },
+
+  select: {
+    width: "100%",
+    padding: 6,
+    borderRadius: 8,
+    border: "1px solid rgba(255, 182, 185, 0.6)",
+  },
+
+  exports: {
+    display: "flex",
+    flexWrap: "wrap",
+    gap: 12,
+  },
+
+  actions: {
+    marginTop: 20,
+    display: "flex",
+    justifyContent: "flex-end",
+    gap: 12,
+  },
+
+  button: {
+    padding: "12px 28px",
+    borderRadius: 50,
+    border: "2px solid rgba(255, 255, 255, 0.8)",
+    background: "linear-gradient(135deg, #ff9a9e 0%, #ffc9a0 100%)",
+    color: "#ffffff",
+    fontSize: 15,
+    fontWeight: 600,
+    cursor: "pointer",
+  },
+
+  secondary: {
+    padding: "12px 28px",
+    borderRadius: 50,
+    border: "2px solid rgba(255, 182, 185, 0.6)",
+    background: "#ffffff",
+    color: "#ff8ba7",
+    fontSize: 15,
+    fontWeight: 600,
+    cursor: "pointer",
+  },
+
+  list: {
+    margin: 0,
+    paddingLeft: 20,
+    color: "#444",
+    lineHeight: 1.6,
+  },
+};
@@ -5,7 +5,11 @@ import {
   Remove,
   UpdateProgress,
   GetProgressHistory,
+  GetLibraryState,
+  RefreshLibrary,
+  GetQueueState,
 } from "../../../wailsjs/go/services/LibraryService";
+import { EventsOn } from "../../../wailsjs/runtime/runtime";
 import { useEffect, useState } from "react";
 import { showToast } from "../../utils/toast";
 
@@ -18,10 +22,36 @@ export default function LibraryPage({
   const [error, setError] = useState(null);
   const [hasPendingSync, setHasPendingSync] = useState(false);
   const [progressHistory, setProgressHistory] = useState({});
+  const [libraryState, setLibraryState] = useState(null);
+  const [pendingChanges, setPendingChanges] = useState(0);
 
   useEffect(() => {
     loadLibrary();
     loadProgressHistory();
+    GetLibraryState().then(setLibraryState).catch(() => {});
+
+    // The stored library is shown first; the backend refreshes it in the
+    // background and sends the server's copy here
+    const offUpdated = EventsOn("library:updated", (update) => {
+      if (update.lists) setLibrary(update.lists);
+      setLibraryState(update.state);
+    });
+
+    // Changes made offline are queued and sent once the server is back
+    GetQueueState()
+      .then((q) => setPendingChanges(q.mutations?.length || 0))
+      .catch(() => {});
+    const offQueue = EventsOn("library:queue", (q) => {
+      setPendingChanges(q.mutations?.length || 0);
+      (q.failed || []).forEach((f) =>
+        showToast(`❌ Offline change to ${f.mutation.manga_id} rejected: ${f.error}`)
+      );
+    });
+
+    return () => {
+      offUpdated();
+      offQueue();
+    };
   }, []);
 
   // Process sync broadcasts from parent
@@ -91,6 +121,14 @@ export default function LibraryPage({
     }
   };
 
+  const refreshLibrary = async () => {
+    try {
+      setLibrary(await RefreshLibrary());
+    } catch (err) {
+      showToast(`❌ ${err?.message || "Refresh failed"}`);
+    }
+  };
+
   const loadProgressHistory = async () => {
     try {
       const history = await GetProgressHistory("");
@@ -141,6 +179,10 @@ export default function LibraryPage({
         force
       );
       await loadLibrary();
+      if (result.queued) {
+        showToast(`📥 Chapter ${result.current_chapter} saved offline, it will sync when the server is back`);
+        return;
+      }
       await loadProgressHistory();
       await handleSyncProgress();
 
@@ -203,6 +245,24 @@ export default function LibraryPage({
         </h1>
         <div style={styles.titleUnderline} />
 
+        {libraryState?.fetched_at && (
+          <div style={styles.freshness}>
+            <span>
+              {libraryState.offline ? "📴 Offline · " : ""}
+              {libraryState.stale ? "⏳ " : ""}
+              Updated {formatUpdated(libraryState.fetched_at)}
+              {pendingChanges > 0 && ` · 📥 ${pendingChanges} change(s) waiting to sync`}
+            </span>
+            <button
+              style={styles.refreshButton}
+              onClick={refreshLibrary}
+              disabled={libraryState.refreshing}
+            >
+              {libraryState.refreshing ? "Refreshing..." : "Refresh"}
+            </button>
+          </div>
+        )}
+
         {/* Sync Notification Button */}
         {hasPendingSync && (
           <button style={styles.syncNotification} onClick={handleSyncProgress}>
@@ -397,6 +457,15 @@ function Section({
   );
 }
 
+// formatUpdated renders the stored library's age, e.g. "3 min ago"
+function formatUpdated(fetchedAt) {
+  const seconds = Math.floor((Date.now() - new Date(fetchedAt)) / 1000);
+  if (seconds < 60) return "just now";
+  if (seconds < 3600) return `${Math.floor(seconds / 60)} min ago`;
+  if (seconds < 86400) return `${Math.floor(seconds / 3600)} h ago`;
+  return new Date(fetchedAt).toLocaleString();
+}
+
 const styles = {
   container: {
     padding: "32px 24px",
@@ -441,6 +510,28 @@ const styles = {
     boxShadow: "0 2px 8px rgba(255, 182, 185, 0.4)",
   },
 
+  freshness: {
+    marginTop: 12,
+    display: "flex",
+    alignItems: "center",
+    justifyContent: "center",
+    gap: 12,
+    color: "#ff8ba7",
+    fontSize: 14,
+    fontWeight: 500,
+  },
+
+  refreshButton: {
+    padding: "6px 16px",
+    borderRadius: 50,
+    border: "2px solid rgba(255, 182, 185, 0.5)",
+    background: "rgba(255, 255, 255, 0.8)",
+    color: "#ff8ba7",
+    fontSize: 13,
+    fontWeight: 600,
+    cursor: "pointer",
+  },
+
   syncNotification: {
     marginTop: 24,
     padding: "16px 32px",
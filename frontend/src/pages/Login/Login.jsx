@@ -39,7 +39,12 @@ export default function LoginPage({ onLogin }) {
         setConfirm("");
       }
     } catch (err) {
-      setError(err?.message || "Request failed");
+      // Signup validation errors list every problem found
+      setError(
+        err?.problems?.length
+          ? err.problems.map((p) => p.message).join("\n")
+          : err?.message || "Request failed"
+      );
     } finally {
       setLoading(false);
     }
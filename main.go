@@ -3,6 +3,8 @@ package main
 import (
 	"embed"
 
+	"mangahub-desktop/backend/httpclient"
+
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
 	"github.com/wailsapp/wails/v2/pkg/options/assetserver"
@@ -27,7 +29,10 @@ func main() {
 		OnStartup:        app.startup,
 
 		OnShutdown: app.shutdown,
+		// Return APIErrors to the frontend as objects rather than strings
+		ErrorFormatter: httpclient.ErrorFormatter,
 		Bind: []interface{}{
+			app,
 			app.Auth,
 			app.Library,
 			app.Notify,
@@ -36,6 +41,12 @@ func main() {
 			app.Sync,
 			app.GRPC,
 			app.Admin,
+			app.Settings,
+			app.Connections,
+			app.Session,
+			app.Accounts,
+			app.Import,
+			app.Export,
 		},
 	})
 